@@ -0,0 +1,413 @@
+package gongo
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// stages that keep the shape of the model documents. $match stages are
+// only translated using the schema until the documents are reshaped
+var schemaPreservingStages = map[string]bool{
+	"$match":  true,
+	"$sort":   true,
+	"$skip":   true,
+	"$limit":  true,
+	"$sample": true,
+}
+
+// Aggregation builds an aggregation pipeline for a model
+type Aggregation struct {
+	model    *Model
+	pipeline []interface{}
+	err      error
+}
+
+// Aggregation creates a new aggregation pipeline builder for the model
+func (c *Model) Aggregation() *Aggregation {
+	return &Aggregation{
+		model:    c,
+		pipeline: make([]interface{}, 0),
+	}
+}
+
+// sets the first error encountered while building the pipeline
+func (c *Aggregation) setError(err error) {
+	if c.err == nil {
+		c.err = err
+	}
+}
+
+// Stage adds a raw pipeline stage
+func (c *Aggregation) Stage(stage interface{}) *Aggregation {
+	c.pipeline = append(c.pipeline, stage)
+	return c
+}
+
+// Match adds a $match stage. the filter is sanitized like other query
+// filters and while the documents have not been reshaped it is also cast
+// to the schema and virtual paths are translated
+func (c *Aggregation) Match(filter interface{}) *Aggregation {
+	return c.Stage(bson.M{"$match": filter})
+}
+
+// Group adds a $group stage
+func (c *Aggregation) Group(group bson.M) *Aggregation {
+	return c.Stage(bson.M{"$group": group})
+}
+
+// Project adds a $project stage
+func (c *Aggregation) Project(projection interface{}) *Aggregation {
+	return c.Stage(bson.M{"$project": projection})
+}
+
+// Lookup adds a $lookup stage joining the collection of the named model
+func (c *Aggregation) Lookup(model, localField, foreignField, as string) *Aggregation {
+	ref := c.model.gongo.M(model)
+	if ref == nil {
+		c.setError(fmt.Errorf("cannot lookup model %q, model is not registered", model))
+		return c
+	}
+	return c.Stage(bson.M{"$lookup": bson.M{
+		"from":         ref.collectionName,
+		"localField":   localField,
+		"foreignField": foreignField,
+		"as":           as,
+	}})
+}
+
+// Unwind adds an $unwind stage for the path
+func (c *Aggregation) Unwind(path string) *Aggregation {
+	if !strings.HasPrefix(path, "$") {
+		path = "$" + path
+	}
+	return c.Stage(bson.M{"$unwind": path})
+}
+
+// Skip adds a $skip stage
+func (c *Aggregation) Skip(skip int64) *Aggregation {
+	return c.Stage(bson.M{"$skip": skip})
+}
+
+// Limit adds a $limit stage
+func (c *Aggregation) Limit(limit int64) *Aggregation {
+	return c.Stage(bson.M{"$limit": limit})
+}
+
+// Sort adds a $sort stage, fields prefixed with - are sorted descending
+func (c *Aggregation) Sort(fields ...string) *Aggregation {
+	sort := bson.D{}
+	for _, field := range fields {
+		order := 1
+		if strings.HasPrefix(field, "-") {
+			order = -1
+			field = strings.TrimPrefix(field, "-")
+		}
+		sort = append(sort, bson.E{Key: field, Value: order})
+	}
+	return c.Stage(bson.M{"$sort": sort})
+}
+
+// Facet adds a $facet stage with a sub-pipeline for each facet
+func (c *Aggregation) Facet(facets map[string]*Aggregation) *Aggregation {
+	facet := bson.M{}
+	for name, sub := range facets {
+		if sub == nil {
+			c.setError(fmt.Errorf("facet %q cannot be nil", name))
+			return c
+		} else if sub.err != nil {
+			c.setError(sub.err)
+			return c
+		}
+		facet[name] = sub.pipeline
+	}
+	return c.Stage(bson.M{"$facet": facet})
+}
+
+// Pipeline returns the stages added to the builder
+func (c *Aggregation) Pipeline() []interface{} {
+	return c.pipeline
+}
+
+// Exec runs the aggregation and returns the results
+func (c *Aggregation) Exec(opts ...*options.AggregateOptions) ([]bson.M, error) {
+	return c.ExecCtx(context.Background(), opts...)
+}
+
+// ExecCtx runs the aggregation and returns the results using the provided context
+func (c *Aggregation) ExecCtx(ctx context.Context, opts ...*options.AggregateOptions) ([]bson.M, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	return c.model.AggregateCtx(ctx, c.pipeline, opts...)
+}
+
+// Iter runs the aggregation and returns a cursor over the results
+func (c *Aggregation) Iter(opts ...*options.AggregateOptions) (*mongo.Cursor, error) {
+	return c.IterCtx(context.Background(), opts...)
+}
+
+// IterCtx runs the aggregation and returns a cursor over the results using the provided context
+func (c *Aggregation) IterCtx(ctx context.Context, opts ...*options.AggregateOptions) (*mongo.Cursor, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	return c.model.aggregate(ctx, c.pipeline, opts...)
+}
+
+// Aggregate runs an aggregation pipeline and returns the results
+func (c *Model) Aggregate(pipeline interface{}, opts ...*options.AggregateOptions) ([]bson.M, error) {
+	return c.AggregateCtx(context.Background(), pipeline, opts...)
+}
+
+// AggregateCtx runs an aggregation pipeline and returns the results using the provided context
+func (c *Model) AggregateCtx(ctx context.Context, pipeline interface{}, opts ...*options.AggregateOptions) ([]bson.M, error) {
+	cur, err := c.aggregate(ctx, pipeline, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	results := make([]bson.M, 0)
+	if err := cur.All(ctx, &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// runs the aggregate wrapped in the aggregate middleware. pre middleware
+// can add stages to the query pipeline or conditions to the query
+// filter which is added to the start of the pipeline as a $match
+func (c *Model) aggregate(ctx context.Context, pipeline interface{}, opts ...*options.AggregateOptions) (*mongo.Cursor, error) {
+	stages, err := pipelineStages(pipeline)
+	if err != nil {
+		return nil, err
+	}
+
+	// apply pre-middleware
+	aggOpts := options.MergeAggregateOptions(opts...)
+	q := &QueryContext{
+		Operation: "aggregate",
+		Model:     c,
+		Filter:    bson.M{},
+		Pipeline:  stages,
+		Options:   aggOpts,
+	}
+	ctx, err = c.applyPreQueryMiddleware(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	stages = q.Pipeline
+	if len(q.Filter) > 0 {
+		stages = append([]interface{}{bson.M{"$match": Trusted(q.Filter)}}, stages...)
+	}
+
+	resolved, err := c.resolvePipeline(ctx, stages, true)
+	if err != nil {
+		return nil, err
+	}
+
+	// perform the aggregation
+	cur, err := c.Collection().Aggregate(ctx, resolved, aggOpts)
+	if err != nil {
+		return nil, c.schema.applyErrorMiddleware(ctx, "aggregate", q.Filter, err)
+	}
+
+	// apply post middleware
+	if err := c.schema.applyPostMiddleware(ctx, "aggregate", q.Filter); err != nil {
+		cur.Close(ctx)
+		return nil, err
+	}
+	return cur, nil
+}
+
+// sanitizes $match stages and translates them using the schema while the
+// documents keep the shape of the model. $lookup model names are resolved
+// to collection names
+func (c *Model) resolvePipeline(ctx context.Context, stages []interface{}, schemaAware bool) ([]interface{}, error) {
+	resolved := make([]interface{}, 0)
+	for _, stage := range stages {
+		operator, value, ok := stageOperator(stage)
+		if !ok {
+			return nil, fmt.Errorf("invalid pipeline stage %v, stages must have exactly one operator", stage)
+		}
+		if !schemaPreservingStages[operator] {
+			schemaAware = false
+		}
+
+		switch operator {
+		case "$match":
+			if schemaAware {
+				query, err := c.buildQuery(ctx, value)
+				if err != nil {
+					return nil, err
+				}
+				value = *query
+				break
+			}
+
+			// reshaped documents are only sanitized, their paths
+			// no longer match the schema
+			match, sanitize, _ := c.filterOptions(value)
+			filter, err := c.filterDocument(match)
+			if err != nil {
+				return nil, err
+			}
+			if value, err = c.sanitizeQuery(filter, sanitize, false); err != nil {
+				return nil, err
+			}
+
+		case "$lookup":
+			lookup, err := c.stageDocument(value)
+			if err != nil {
+				return nil, err
+			}
+			if from, ok := lookup["from"].(string); ok {
+				if ref := c.gongo.M(from); ref != nil {
+					lookup["from"] = ref.collectionName
+				}
+			}
+			value = lookup
+
+		case "$facet":
+			facets, err := c.stageDocument(value)
+			if err != nil {
+				return nil, err
+			}
+			for name, sub := range facets {
+				subStages, err := pipelineStages(sub)
+				if err != nil {
+					return nil, err
+				}
+				if facets[name], err = c.resolvePipeline(ctx, subStages, schemaAware); err != nil {
+					return nil, err
+				}
+			}
+			value = facets
+		}
+		resolved = append(resolved, bson.D{{Key: operator, Value: value}})
+	}
+	return resolved, nil
+}
+
+// converts a stage value to a document. ordered documents and elements are
+// converted explicitly because decoding them produces Key and Value fields.
+// nested values are left as is so that sub-pipelines keep their order
+func (c *Model) stageDocument(value interface{}) (bson.M, error) {
+	switch v := value.(type) {
+	case bson.D:
+		m := bson.M{}
+		for _, e := range v {
+			m[e.Key] = e.Value
+		}
+		return m, nil
+	case primitive.E:
+		return bson.M{v.Key: v.Value}, nil
+	case bson.M:
+		m := bson.M{}
+		for key, val := range v {
+			m[key] = val
+		}
+		return m, nil
+	case map[string]interface{}:
+		return c.stageDocument(bson.M(v))
+	}
+
+	m := bson.M{}
+	if err := c.gongo.weakDecode(value, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// converts a filter to a document, ordered documents and elements
+// in the filter are converted to documents at every level
+func (c *Model) filterDocument(value interface{}) (bson.M, error) {
+	if m, ok := unorderedValue(value).(bson.M); ok {
+		return m, nil
+	}
+	m := bson.M{}
+	if err := c.gongo.weakDecode(value, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// converts ordered documents and elements to bson.M, documents
+// and arrays are converted recursively
+func unorderedValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case bson.D:
+		m := bson.M{}
+		for _, e := range v {
+			m[e.Key] = unorderedValue(e.Value)
+		}
+		return m
+	case primitive.E:
+		return bson.M{v.Key: unorderedValue(v.Value)}
+	case bson.M:
+		m := bson.M{}
+		for key, val := range v {
+			m[key] = unorderedValue(val)
+		}
+		return m
+	case map[string]interface{}:
+		return unorderedValue(bson.M(v))
+	case bson.A:
+		return unorderedValue([]interface{}(v))
+	case []interface{}:
+		a := make([]interface{}, len(v))
+		for i, val := range v {
+			a[i] = unorderedValue(val)
+		}
+		return a
+	}
+	return value
+}
+
+// returns the operator and value of a single pipeline stage
+func stageOperator(stage interface{}) (string, interface{}, bool) {
+	switch s := stage.(type) {
+	case bson.D:
+		if len(s) == 1 {
+			return s[0].Key, s[0].Value, true
+		}
+	case bson.M:
+		for k, v := range s {
+			return k, v, len(s) == 1
+		}
+	case map[string]interface{}:
+		return stageOperator(bson.M(s))
+	case *bson.M:
+		return stageOperator(*s)
+	}
+	return "", nil, false
+}
+
+// converts a pipeline of any slice type to a list of stages
+func pipelineStages(pipeline interface{}) ([]interface{}, error) {
+	if pipeline == nil {
+		return []interface{}{}, nil
+	} else if stages, ok := pipeline.([]interface{}); ok {
+		return append([]interface{}{}, stages...), nil
+	}
+
+	rv := reflect.ValueOf(pipeline)
+	for rv.Kind() == reflect.Ptr {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, fmt.Errorf("pipeline must be a list of stages")
+	}
+	stages := make([]interface{}, 0)
+	for i := 0; i < rv.Len(); i++ {
+		stages = append(stages, rv.Index(i).Interface())
+	}
+	return stages, nil
+}
@@ -0,0 +1,271 @@
+package gongo
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestAggregationPipeline(t *testing.T) {
+	g := New()
+	orderSchema := Schema{
+		Fields: SchemaFieldMap{
+			"customer": {
+				Type: ObjectIDType,
+			},
+			"total": {
+				Type: IntType,
+			},
+		},
+	}
+	customerSchema := Schema{
+		Fields: SchemaFieldMap{
+			"name": {
+				Type: StringType,
+			},
+		},
+	}
+	orders, err := g.Model("Order", &orderSchema)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if _, err := g.Model("Customer", &customerSchema); err != nil {
+		t.Error(err)
+		return
+	}
+
+	id := primitive.NewObjectID()
+	agg := orders.Aggregation().
+		Match(bson.M{"id": id.Hex(), "total": bson.M{"$gt": "10"}}).
+		Lookup("Customer", "customer", "_id", "customer").
+		Unwind("customer").
+		Group(bson.M{"_id": "$customer._id", "total": bson.M{"$sum": "$total"}}).
+		Match(bson.M{"total": bson.M{"$gt": "100"}}).
+		Sort("-total").
+		Facet(map[string]*Aggregation{
+			"top": orders.Aggregation().Limit(1),
+		})
+	if agg.err != nil {
+		t.Error(agg.err)
+		return
+	}
+
+	stages, err := orders.resolvePipeline(context.Background(), agg.Pipeline(), true)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(stages) != 7 {
+		t.Errorf("expected 7 stages, actual %d", len(stages))
+		return
+	}
+
+	// the first match is translated and cast
+	match := stages[0].(bson.D)[0].Value.(bson.M)
+	if match["_id"] != id {
+		t.Errorf("expected virtual id to be translated, actual %v", match)
+		return
+	}
+	if gt := match["total"].(bson.M)["$gt"]; gt != 10 {
+		t.Errorf("expected total to be cast, actual %v", gt)
+		return
+	}
+
+	// the lookup resolves the collection name
+	lookup := stages[1].(bson.D)[0].Value.(bson.M)
+	if lookup["from"] != "customers" {
+		t.Errorf("expected customers collection, actual %v", lookup["from"])
+		return
+	}
+
+	// matches after reshaping are left as is
+	match = stages[4].(bson.D)[0].Value.(bson.M)
+	if gt := match["total"].(bson.M)["$gt"]; gt != "100" {
+		t.Errorf("expected reshaped match to be left as is, actual %v", gt)
+		return
+	}
+
+	if _, err := orders.Aggregation().Lookup("Missing", "a", "b", "c").Exec(); err == nil {
+		t.Error("expected error looking up unregistered model")
+	}
+}
+
+func TestAggregatePipelineOrderedDocuments(t *testing.T) {
+	g := New()
+	userSchema := Schema{
+		Fields: SchemaFieldMap{
+			"age": {
+				Type: IntType,
+			},
+		},
+	}
+	users, err := g.Model("User", &userSchema)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if _, err := g.Model("Customer", &Schema{}); err != nil {
+		t.Error(err)
+		return
+	}
+
+	sort := bson.D{{Key: "age", Value: -1}, {Key: "_id", Value: 1}}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{{Key: "age", Value: "5"}}}},
+		{{Key: "$match", Value: bson.D{{Key: "age", Value: bson.D{{Key: "$gt", Value: "1"}}}}}},
+		{{Key: "$lookup", Value: bson.D{
+			{Key: "from", Value: "Customer"},
+			{Key: "localField", Value: "customer"},
+			{Key: "foreignField", Value: "_id"},
+			{Key: "as", Value: "customer"},
+		}}},
+		{{Key: "$facet", Value: bson.D{
+			{Key: "sorted", Value: mongo.Pipeline{{{Key: "$sort", Value: sort}}}},
+		}}},
+	}
+	stages, err := pipelineStages(pipeline)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	resolved, err := users.resolvePipeline(context.Background(), stages, true)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if match := resolved[0].(bson.D)[0].Value.(bson.M); match["age"] != 5 {
+		t.Errorf("expected age to be cast, actual %v", match)
+		return
+	}
+	match := resolved[1].(bson.D)[0].Value.(bson.M)
+	if gt := match["age"].(bson.M)["$gt"]; gt != 1 {
+		t.Errorf("expected nested operator to be cast, actual %v", match)
+		return
+	}
+	lookup := resolved[2].(bson.D)[0].Value.(bson.M)
+	if lookup["from"] != "customers" || lookup["as"] != "customer" {
+		t.Errorf("expected lookup to be resolved, actual %v", lookup)
+		return
+	}
+	facet := resolved[3].(bson.D)[0].Value.(bson.M)
+	sorted := facet["sorted"].([]interface{})[0].(bson.D)[0].Value
+	if !reflect.DeepEqual(sort, sorted) {
+		t.Errorf("expected facet sort order to be kept, actual %v", sorted)
+		return
+	}
+
+	// the builder match accepts ordered documents
+	agg := users.Aggregation().Match(bson.D{{Key: "age", Value: "5"}})
+	if agg.err != nil {
+		t.Error(agg.err)
+		return
+	}
+	resolved, err = users.resolvePipeline(context.Background(), agg.Pipeline(), true)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if match := resolved[0].(bson.D)[0].Value.(bson.M); match["age"] != 5 {
+		t.Errorf("expected ordered match to be converted, actual %v", match)
+	}
+}
+
+func TestAggregationSanitize(t *testing.T) {
+	g := New(&Options{SanitizeFilter: true})
+	schema := Schema{
+		Fields: SchemaFieldMap{
+			"name": {
+				Type: StringType,
+			},
+		},
+	}
+	users, err := g.Model("User", &schema)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	// match operators are rejected
+	var unsafe *UnsafeFilterError
+	_, err = users.Aggregation().Match(bson.M{"$where": "true"}).Exec()
+	if !errors.As(err, &unsafe) || unsafe.Key != "$where" {
+		t.Errorf("expected unsafe filter error, actual %v", err)
+		return
+	}
+
+	// matches after reshaping are sanitized too
+	_, err = users.Aggregation().
+		Group(bson.M{"_id": "$name"}).
+		Match(bson.M{"$where": "true"}).
+		Exec()
+	if !errors.As(err, &unsafe) {
+		t.Errorf("expected unsafe filter error after reshaping, actual %v", err)
+		return
+	}
+	stages, err := users.resolvePipeline(context.Background(), []interface{}{
+		bson.M{"$group": bson.M{"_id": "$name"}},
+		bson.M{"$match": bson.M{"_id": bson.M{"$ne": "foo"}}},
+	}, true)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	expected := bson.M{"_id": bson.M{"$eq": bson.M{"$ne": "foo"}}}
+	if match := stages[1].(bson.D)[0].Value; !reflect.DeepEqual(expected, match) {
+		t.Errorf("expected reshaped match to be escaped, actual %v", match)
+		return
+	}
+
+	// trusted matches are left as is
+	stages, err = users.resolvePipeline(context.Background(), []interface{}{
+		bson.M{"$match": Trusted(bson.M{"$where": "true"})},
+	}, true)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if match := stages[0].(bson.D)[0].Value.(bson.M); match["$where"] != "true" {
+		t.Errorf("expected trusted match to be kept, actual %v", match)
+	}
+}
+
+func TestAggregateMiddleware(t *testing.T) {
+	g := New()
+	schema := Schema{
+		Fields: SchemaFieldMap{
+			"tenant": {
+				Type: StringType,
+			},
+		},
+	}
+	users, err := g.Model("User", &schema)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	errStop := errors.New("stop")
+	var pipeline []interface{}
+	users.schema.Pre("aggregate", func(ctx context.Context, filter bson.M, next NextFunc) error {
+		query, _ := QueryFromContext(ctx)
+		filter["tenant"] = "foo"
+		query.Pipeline = append(query.Pipeline, bson.M{"$limit": 10})
+		pipeline = query.Pipeline
+		return errStop
+	})
+
+	if _, err := users.Aggregate([]bson.M{{"$sort": bson.M{"tenant": 1}}}); err != errStop {
+		t.Errorf("expected middleware error, actual %v", err)
+		return
+	}
+	if len(pipeline) != 2 {
+		t.Errorf("expected injected stage, actual %v", pipeline)
+	}
+}
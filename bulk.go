@@ -0,0 +1,451 @@
+package gongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// BulkResult the result of a bulk write. errors are keyed by
+// the index of the document or operation that failed
+type BulkResult struct {
+	InsertedCount    int64
+	MatchedCount     int64
+	ModifiedCount    int64
+	DeletedCount     int64
+	UpsertedCount    int64
+	InsertedIDs      map[int]interface{}
+	UpsertedIDs      map[int]interface{}
+	ValidationErrors map[int]error
+	WriteErrors      map[int]error
+
+	// WriteConcernError is set when the writes were performed but
+	// the write concern could not be satisfied
+	WriteConcernError error
+}
+
+// creates a new empty bulk result
+func newBulkResult() *BulkResult {
+	return &BulkResult{
+		InsertedIDs:      make(map[int]interface{}),
+		UpsertedIDs:      make(map[int]interface{}),
+		ValidationErrors: make(map[int]error),
+		WriteErrors:      make(map[int]error),
+	}
+}
+
+// HasErrors returns true if any document or operation failed
+func (c *BulkResult) HasErrors() bool {
+	return len(c.ValidationErrors) > 0 || len(c.WriteErrors) > 0 || c.WriteConcernError != nil
+}
+
+// BulkError returned when one or more documents or operations
+// of a bulk write fail, the result contains the individual errors
+type BulkError struct {
+	Result *BulkResult
+}
+
+// Error implements the error interface
+func (c *BulkError) Error() string {
+	msg := fmt.Sprintf(
+		"bulk write failed: %d validation errors, %d write errors",
+		len(c.Result.ValidationErrors),
+		len(c.Result.WriteErrors),
+	)
+	if c.Result.WriteConcernError != nil {
+		msg += ", write concern error: " + c.Result.WriteConcernError.Error()
+	}
+	return msg
+}
+
+// InsertMany validates and inserts many documents in a single request
+func (c *Model) InsertMany(docs []interface{}, opts ...*options.InsertManyOptions) (*BulkResult, error) {
+	return c.InsertManyCtx(context.Background(), docs, opts...)
+}
+
+// InsertManyCtx validates and inserts many documents in a single request using
+// the provided context. each document is walked with full validation and the
+// save middleware. ordered inserts stop at the first document that fails,
+// unordered inserts submit every valid document
+func (c *Model) InsertManyCtx(ctx context.Context, docs []interface{}, opts ...*options.InsertManyOptions) (*BulkResult, error) {
+	result := newBulkResult()
+	insertOpts := options.MergeInsertManyOptions(opts...)
+	ordered := insertOpts.Ordered == nil || *insertOpts.Ordered
+
+	// validate each document, indexes maps the submitted
+	// documents back to their position in docs
+	documents := make([]interface{}, 0)
+	prepared := make([]bson.M, 0)
+	indexes := make([]int, 0)
+	for i, doc := range docs {
+		document, err := c.prepareInsert(ctx, doc)
+		if err != nil {
+			result.ValidationErrors[i] = err
+			if ordered {
+				break
+			}
+			continue
+		}
+		documents = append(documents, document)
+		prepared = append(prepared, document)
+		indexes = append(indexes, i)
+	}
+
+	// perform the insert
+	if len(documents) > 0 {
+		_, err := c.Collection().InsertMany(ctx, documents, insertOpts)
+		indexes, err = c.applyWriteErrors(result, err, indexes, ordered, func(n int, err error) error {
+			return c.schema.applyErrorMiddleware(ctx, "save", prepared[n], err)
+		})
+		if err != nil {
+			return result, err
+		}
+	}
+
+	// record the inserted documents and apply post middleware. when the
+	// write concern is not satisfied the inserts are not reported as saved
+	for n, i := range indexes {
+		if _, ok := result.WriteErrors[i]; ok {
+			continue
+		}
+		saved := prepared[n]
+		result.InsertedIDs[i] = saved["_id"]
+		result.InsertedCount++
+		if result.WriteConcernError != nil {
+			continue
+		}
+		if err := afterCommit(ctx, func(ctx context.Context) error {
+			return c.schema.applyPostMiddleware(ctx, "save", saved)
+		}); err != nil {
+			return result, err
+		}
+	}
+
+	if result.HasErrors() {
+		return result, &BulkError{Result: result}
+	}
+	return result, nil
+}
+
+// records the errors of a bulk write exception on the result and returns the
+// indexes of the writes that were performed. errors other than a bulk write
+// exception are returned as is. ordered writes stop at the first write error.
+// onError is called with the position of each failed write in indexes
+func (c *Model) applyWriteErrors(result *BulkResult, err error, indexes []int, ordered bool, onError func(n int, err error) error) ([]int, error) {
+	var bwe mongo.BulkWriteException
+	if !errors.As(err, &bwe) {
+		return indexes, err
+	}
+
+	for _, we := range bwe.WriteErrors {
+		if we.Index >= 0 && we.Index < len(indexes) {
+			writeErr := mongo.WriteException{WriteErrors: []mongo.WriteError{we.WriteError}}
+			result.WriteErrors[indexes[we.Index]] = onError(we.Index, writeErr)
+		}
+	}
+	if bwe.WriteConcernError != nil {
+		result.WriteConcernError = bwe.WriteConcernError
+	}
+	if ordered && len(bwe.WriteErrors) > 0 {
+		// nothing after the first failure is performed
+		indexes = indexes[:bwe.WriteErrors[0].Index+1]
+	}
+	return indexes, nil
+}
+
+// prepares a new document for insert the same way Document.Save
+// does, returning the walked document with an id assigned
+func (c *Model) prepareInsert(ctx context.Context, document interface{}) (bson.M, error) {
+	if document == nil {
+		return nil, fmt.Errorf("no document provided")
+	}
+	doc := bson.M{}
+	if err := c.gongo.weakDecode(document, &doc); err != nil {
+		return nil, err
+	}
+
+	// apply pre-middleware
+	if err := c.schema.applyPreMiddleware(ctx, "save", doc); err != nil {
+		return nil, err
+	}
+	c.schema.setTimestamps(doc, true)
+
+	// walk document with full validation
+	if err := c.schema.applyPreMiddleware(ctx, "validate", doc); err != nil {
+		return nil, err
+	}
+	walked, err := c.schema.walk(doc, []string{}, &walkOptions{
+		applySetters:     true,
+		applyDefaults:    true,
+		castTypes:        true,
+		validateTypes:    true,
+		validateCustom:   true,
+		validateRequired: true,
+	})
+	// validation errors pass through the validate then the save error middleware
+	if err != nil {
+		err = c.schema.applyErrorMiddleware(ctx, "validate", doc, err)
+		return nil, c.schema.applyErrorMiddleware(ctx, "save", doc, err)
+	}
+	if err := c.schema.applyPostMiddleware(ctx, "validate", cloneDocument(walked)); err != nil {
+		return nil, err
+	}
+
+	prepared := *walked
+	if id, ok := prepared["_id"]; !ok || id == nil {
+		prepared["_id"] = primitive.NewObjectID()
+	}
+	c.schema.setVersion(prepared)
+	return prepared, nil
+}
+
+const bulkContextKey contextKey = "gongo.bulk"
+
+// BulkContext identifies the bulk operation middleware is being run for
+type BulkContext struct {
+	Index     int
+	Operation string
+}
+
+// BulkFromContext returns the bulk operation the middleware is being
+// run for, ok is false when the operation is not part of a bulk write
+func BulkFromContext(ctx context.Context) (*BulkContext, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	bulk, ok := ctx.Value(bulkContextKey).(*BulkContext)
+	return bulk, ok
+}
+
+// a single operation in a bulk write
+type bulkOperation struct {
+	operation string
+	filter    interface{}
+	document  interface{}
+}
+
+// Bulk builds a bulk write whose operations are validated against
+// the schema before being sent in a single request
+type Bulk struct {
+	model      *Model
+	operations []*bulkOperation
+	ordered    bool
+}
+
+// Bulk creates a new bulk write builder for the model
+func (c *Model) Bulk() *Bulk {
+	return &Bulk{
+		model:      c,
+		operations: make([]*bulkOperation, 0),
+		ordered:    true,
+	}
+}
+
+// adds an operation to the bulk write
+func (c *Bulk) add(operation string, filter interface{}, document interface{}) *Bulk {
+	c.operations = append(c.operations, &bulkOperation{
+		operation: operation,
+		filter:    filter,
+		document:  document,
+	})
+	return c
+}
+
+// Insert adds an insert of the document
+func (c *Bulk) Insert(document interface{}) *Bulk {
+	return c.add("insertOne", nil, document)
+}
+
+// UpdateOne adds an update of the first document matching the filter
+func (c *Bulk) UpdateOne(filter interface{}, update interface{}) *Bulk {
+	return c.add("updateOne", filter, update)
+}
+
+// UpdateMany adds an update of all documents matching the filter
+func (c *Bulk) UpdateMany(filter interface{}, update interface{}) *Bulk {
+	return c.add("updateMany", filter, update)
+}
+
+// ReplaceOne adds a replacement of the first document matching the filter
+func (c *Bulk) ReplaceOne(filter interface{}, replacement interface{}) *Bulk {
+	return c.add("replaceOne", filter, replacement)
+}
+
+// DeleteOne adds a delete of the first document matching the filter
+func (c *Bulk) DeleteOne(filter interface{}) *Bulk {
+	return c.add("deleteOne", filter, nil)
+}
+
+// DeleteMany adds a delete of all documents matching the filter
+func (c *Bulk) DeleteMany(filter interface{}) *Bulk {
+	return c.add("deleteMany", filter, nil)
+}
+
+// Ordered sets whether the operations are performed in order, ordered
+// bulk writes stop at the first operation that fails
+func (c *Bulk) Ordered(ordered bool) *Bulk {
+	c.ordered = ordered
+	return c
+}
+
+// Len returns the number of operations
+func (c *Bulk) Len() int {
+	return len(c.operations)
+}
+
+// Exec validates and performs the bulk write
+func (c *Bulk) Exec(opts ...*options.BulkWriteOptions) (*BulkResult, error) {
+	return c.ExecCtx(context.Background(), opts...)
+}
+
+// ExecCtx validates and performs the bulk write using the provided context.
+// middleware for each operation is run with a context that carries the
+// operation index, see BulkFromContext. the ordered option is set with Ordered
+func (c *Bulk) ExecCtx(ctx context.Context, opts ...*options.BulkWriteOptions) (*BulkResult, error) {
+	model := c.model
+	result := newBulkResult()
+
+	// prepare each operation, indexes maps the submitted
+	// models back to their position in the builder
+	writes := make([]mongo.WriteModel, 0)
+	prepared := make([]bson.M, 0)
+	indexes := make([]int, 0)
+	for i, op := range c.operations {
+		write, document, err := model.prepareBulkOperation(c.operationContext(ctx, i), op)
+		if err != nil {
+			result.ValidationErrors[i] = err
+			if c.ordered {
+				break
+			}
+			continue
+		}
+		writes = append(writes, write)
+		prepared = append(prepared, document)
+		indexes = append(indexes, i)
+	}
+
+	// perform the bulk write
+	if len(writes) > 0 {
+		bulkOpts := options.MergeBulkWriteOptions(opts...).SetOrdered(c.ordered)
+		res, err := model.Collection().BulkWrite(ctx, writes, bulkOpts)
+		indexes, err = model.applyWriteErrors(result, err, indexes, c.ordered, func(n int, err error) error {
+			i := indexes[n]
+			return model.applyBulkErrorMiddleware(c.operationContext(ctx, i), c.operations[i].operation, prepared[n], err)
+		})
+		if err != nil {
+			return result, err
+		}
+
+		if res != nil {
+			result.MatchedCount = res.MatchedCount
+			result.ModifiedCount = res.ModifiedCount
+			result.DeletedCount = res.DeletedCount
+			result.UpsertedCount = res.UpsertedCount
+			for index, id := range res.UpsertedIDs {
+				if int(index) < len(indexes) {
+					result.UpsertedIDs[indexes[index]] = id
+				}
+			}
+		}
+	}
+
+	// record the inserted documents and apply post middleware. when the
+	// write concern is not satisfied the writes are not reported as saved
+	for n, i := range indexes {
+		if _, ok := result.WriteErrors[i]; ok {
+			continue
+		}
+		op := c.operations[i]
+		document := prepared[n]
+		if op.operation == "insertOne" {
+			result.InsertedIDs[i] = document["_id"]
+			result.InsertedCount++
+		}
+		if result.WriteConcernError != nil {
+			continue
+		}
+
+		opCtx := c.operationContext(ctx, i)
+		switch op.operation {
+		case "insertOne":
+			if err := afterCommit(opCtx, func(ctx context.Context) error {
+				return model.schema.applyPostMiddleware(ctx, "save", document)
+			}); err != nil {
+				return result, err
+			}
+		case "updateOne", "updateMany", "replaceOne":
+			if err := model.applyUpdatePostMiddleware(opCtx, op.operation, document); err != nil {
+				return result, err
+			}
+		default:
+			if err := model.schema.applyPostMiddleware(opCtx, op.operation, document); err != nil {
+				return result, err
+			}
+		}
+	}
+
+	if result.HasErrors() {
+		return result, &BulkError{Result: result}
+	}
+	return result, nil
+}
+
+// returns a context carrying the bulk operation at index i
+func (c *Bulk) operationContext(ctx context.Context, i int) context.Context {
+	return context.WithValue(ctx, bulkContextKey, &BulkContext{Index: i, Operation: c.operations[i].operation})
+}
+
+// validates a bulk operation and creates its write model, the returned
+// document is the one passed to the post middleware
+func (c *Model) prepareBulkOperation(ctx context.Context, op *bulkOperation) (mongo.WriteModel, bson.M, error) {
+	switch op.operation {
+	case "insertOne":
+		document, err := c.prepareInsert(ctx, op.document)
+		if err != nil {
+			return nil, nil, err
+		}
+		return mongo.NewInsertOneModel().SetDocument(document), document, nil
+
+	case "updateOne", "updateMany":
+		query, update, err := c.prepareUpdate(ctx, op.operation, op.filter, op.document)
+		if err != nil {
+			return nil, nil, err
+		} else if op.operation == "updateMany" {
+			return mongo.NewUpdateManyModel().SetFilter(query).SetUpdate(update), update, nil
+		}
+		return mongo.NewUpdateOneModel().SetFilter(query).SetUpdate(update), update, nil
+
+	case "replaceOne":
+		query, document, err := c.prepareReplace(ctx, op.filter, op.document)
+		if err != nil {
+			return nil, nil, err
+		}
+		return mongo.NewReplaceOneModel().SetFilter(query).SetReplacement(document), document, nil
+
+	case "deleteOne", "deleteMany":
+		query, err := c.prepareDelete(ctx, op.operation, op.filter)
+		if err != nil {
+			return nil, nil, err
+		} else if op.operation == "deleteMany" {
+			return mongo.NewDeleteManyModel().SetFilter(query), query, nil
+		}
+		return mongo.NewDeleteOneModel().SetFilter(query), query, nil
+	}
+	return nil, nil, fmt.Errorf("unsupported bulk operation %q", op.operation)
+}
+
+// applies the error middleware for a failed bulk operation
+func (c *Model) applyBulkErrorMiddleware(ctx context.Context, operation string, document bson.M, err error) error {
+	switch operation {
+	case "insertOne":
+		return c.schema.applyErrorMiddleware(ctx, "save", document, err)
+	case "updateOne", "updateMany", "replaceOne":
+		return c.applyUpdateErrorMiddleware(ctx, operation, document, err)
+	}
+	return c.schema.applyErrorMiddleware(ctx, operation, document, err)
+}
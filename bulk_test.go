@@ -0,0 +1,186 @@
+package gongo
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestInsertManyValidation(t *testing.T) {
+	g := New()
+	schema := Schema{
+		Fields: SchemaFieldMap{
+			"name": {
+				Type:     StringType,
+				Required: true,
+			},
+		},
+	}
+	users, err := g.Model("User", &schema)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	docs := []interface{}{
+		bson.M{"age": 1},
+		bson.M{"name": true},
+	}
+
+	// ordered inserts stop at the first invalid document
+	result, err := users.InsertMany(docs)
+	if _, ok := err.(*BulkError); !ok {
+		t.Errorf("expected bulk error, actual %v", err)
+		return
+	}
+	if len(result.ValidationErrors) != 1 || result.ValidationErrors[0] == nil {
+		t.Errorf("expected validation error at index 0, actual %v", result.ValidationErrors)
+		return
+	}
+
+	// unordered inserts report every invalid document
+	result, err = users.InsertMany(docs, options.InsertMany().SetOrdered(false))
+	if _, ok := err.(*BulkError); !ok {
+		t.Errorf("expected bulk error, actual %v", err)
+		return
+	}
+	if len(result.ValidationErrors) != 2 || result.InsertedCount != 0 {
+		t.Errorf("expected 2 validation errors, actual %v", result.ValidationErrors)
+		return
+	}
+	if _, ok := result.ValidationErrors[1].(*ValidationError); !ok {
+		t.Errorf("expected typed validation error, actual %v", result.ValidationErrors[1])
+	}
+}
+
+func TestBulkValidation(t *testing.T) {
+	g := New()
+	schema := Schema{
+		Fields: SchemaFieldMap{
+			"name": {
+				Type:     StringType,
+				Required: true,
+			},
+			"age": {
+				Type: IntType,
+			},
+		},
+	}
+	users, err := g.Model("User", &schema)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	errStop := errors.New("stop")
+	var bulk *BulkContext
+	users.schema.Pre("deleteOne", func(ctx context.Context, query bson.M, next NextFunc) error {
+		bulk, _ = BulkFromContext(ctx)
+		return errStop
+	})
+
+	result, err := users.Bulk().
+		Ordered(false).
+		Insert(bson.M{"age": 1}).
+		UpdateOne(bson.M{"name": "foo"}, bson.M{"$set": bson.M{"age": "foo"}}).
+		DeleteOne(bson.M{"name": "foo"}).
+		Exec()
+	if _, ok := err.(*BulkError); !ok {
+		t.Errorf("expected bulk error, actual %v", err)
+		return
+	}
+	if len(result.ValidationErrors) != 3 {
+		t.Errorf("expected 3 validation errors, actual %v", result.ValidationErrors)
+		return
+	}
+	if result.ValidationErrors[2] != errStop {
+		t.Errorf("expected middleware error at index 2, actual %v", result.ValidationErrors[2])
+		return
+	}
+	if bulk == nil || bulk.Index != 2 || bulk.Operation != "deleteOne" {
+		t.Errorf("expected bulk context for index 2, actual %v", bulk)
+	}
+}
+
+func TestBulkWriteErrors(t *testing.T) {
+	g := New()
+	schema := Schema{
+		Fields: SchemaFieldMap{
+			"name": {
+				Type: StringType,
+			},
+		},
+	}
+	users, err := g.Model("User", &schema)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	// records the position of each failed write
+	var failed []int
+	onError := func(n int, err error) error {
+		failed = append(failed, n)
+		return err
+	}
+
+	// a write concern error without write errors is reported
+	result := newBulkResult()
+	wce := mongo.BulkWriteException{
+		WriteConcernError: &mongo.WriteConcernError{Code: 64, Message: "waiting for replication timed out"},
+	}
+	indexes, err := users.applyWriteErrors(result, wce, []int{0, 2}, true, onError)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(indexes) != 2 || result.WriteConcernError == nil || !result.HasErrors() {
+		t.Errorf("expected write concern error, actual %v", result.WriteConcernError)
+		return
+	}
+
+	// ordered writes stop at the first write error
+	result = newBulkResult()
+	we := mongo.BulkWriteException{
+		WriteErrors: []mongo.BulkWriteError{
+			{WriteError: mongo.WriteError{Index: 0, Code: 11000, Message: "duplicate key"}},
+		},
+	}
+	indexes, err = users.applyWriteErrors(result, we, []int{0, 2}, true, onError)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(indexes) != 1 || result.WriteErrors[0] == nil || result.WriteConcernError != nil {
+		t.Errorf("expected write error at index 0, actual %v", result.WriteErrors)
+		return
+	}
+
+	// unordered writes map the submitted index back to the operation
+	result = newBulkResult()
+	we.WriteErrors[0].Index = 1
+	indexes, err = users.applyWriteErrors(result, we, []int{0, 2}, false, onError)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(indexes) != 2 || result.WriteErrors[2] == nil {
+		t.Errorf("expected write error at index 2, actual %v", result.WriteErrors)
+		return
+	}
+	if !reflect.DeepEqual([]int{0, 1}, failed) {
+		t.Errorf("expected error middleware for writes [0 1], actual %v", failed)
+		return
+	}
+
+	// other errors are returned as is
+	errStop := errors.New("stop")
+	if _, err := users.applyWriteErrors(newBulkResult(), errStop, []int{0}, true, onError); err != errStop {
+		t.Errorf("expected error to be returned, actual %v", err)
+	}
+}
@@ -0,0 +1,165 @@
+package gongo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type testContextKey string
+
+func TestContextValues(t *testing.T) {
+	g := New()
+	schema := Schema{
+		Fields: SchemaFieldMap{
+			"name": {
+				Type: StringType,
+			},
+		},
+	}
+
+	// record the context value seen by each middleware and stop
+	// the operation before it reaches the server
+	key := testContextKey("request")
+	errStop := errors.New("stop")
+	values := map[string]interface{}{}
+	for _, op := range []string{"find", "findOne", "findOneAndUpdate", "findOneAndDelete"} {
+		operation := op
+		if err := schema.Pre(operation, func(ctx context.Context, query bson.M, next NextFunc) error {
+			values["pre "+operation] = ctx.Value(key)
+			return errStop
+		}); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+	if err := schema.Pre("save", func(ctx context.Context, doc bson.M, next NextFunc) error {
+		values["pre save"] = ctx.Value(key)
+		return next()
+	}); err != nil {
+		t.Error(err)
+		return
+	}
+	if err := schema.Post("validate", func(ctx context.Context, doc bson.M, next NextFunc) error {
+		values["post validate"] = ctx.Value(key)
+		return errStop
+	}); err != nil {
+		t.Error(err)
+		return
+	}
+
+	users, err := g.Model("User", &schema)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	ctx := context.WithValue(context.Background(), key, "foo")
+	filter := bson.M{"name": "foo"}
+
+	if _, err := users.FindCtx(ctx, filter); err != errStop {
+		t.Errorf("expected find middleware error, actual %v", err)
+		return
+	}
+	if _, err := users.FindOneCtx(ctx, filter); err != errStop {
+		t.Errorf("expected findOne middleware error, actual %v", err)
+		return
+	}
+	if _, err := users.FindOneAndUpdateCtx(ctx, filter, bson.M{"$set": bson.M{"name": "bar"}}); err != errStop {
+		t.Errorf("expected findOneAndUpdate middleware error, actual %v", err)
+		return
+	}
+	if _, err := users.FindOneAndDeleteCtx(ctx, filter); err != errStop {
+		t.Errorf("expected findOneAndDelete middleware error, actual %v", err)
+		return
+	}
+	for _, op := range []string{"pre find", "pre findOne", "pre findOneAndUpdate", "pre findOneAndDelete"} {
+		if values[op] != "foo" {
+			t.Errorf("expected %s middleware to receive the context value, actual %v", op, values[op])
+			return
+		}
+	}
+
+	user, err := users.New(bson.M{"name": "foo"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if err := user.SaveCtx(ctx); err != errStop {
+		t.Errorf("expected save middleware error, actual %v", err)
+		return
+	}
+	if values["pre save"] != "foo" || values["post validate"] != "foo" {
+		t.Errorf("expected save middleware to receive the context value, actual %v", values)
+		return
+	}
+
+	delete(values, "pre save")
+	delete(values, "post validate")
+	if _, err := users.CreateCtx(ctx, bson.M{"name": "foo"}); err != errStop {
+		t.Errorf("expected create middleware error, actual %v", err)
+		return
+	}
+	if values["pre save"] != "foo" || values["post validate"] != "foo" {
+		t.Errorf("expected create middleware to receive the context value, actual %v", values)
+	}
+}
+
+func TestContextCanceled(t *testing.T) {
+	g := New()
+	schema := Schema{
+		Fields: SchemaFieldMap{
+			"name": {
+				Type: StringType,
+			},
+		},
+	}
+	users, err := g.Model("User", &schema)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	// the client connects lazily so the unreachable server is only
+	// selected when an operation is performed
+	clientOptions := options.Client().
+		ApplyURI("mongodb://127.0.0.1:1").
+		SetServerSelectionTimeout(5 * time.Second)
+	client, err := mongo.Connect(context.Background(), clientOptions)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer client.Disconnect(context.Background())
+	g.client = client
+	g.database = "gongo"
+	g.connected = true
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	filter := bson.M{"_id": primitive.NewObjectID()}
+
+	user, err := users.New(bson.M{"name": "foo"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	errs := map[string]error{}
+	errs["SaveCtx"] = user.SaveCtx(ctx)
+	_, errs["FindCtx"] = users.FindCtx(ctx, filter)
+	_, errs["FindOneCtx"] = users.FindOneCtx(ctx, filter)
+	_, errs["FindOneAndUpdateCtx"] = users.FindOneAndUpdateCtx(ctx, filter, bson.M{"$set": bson.M{"name": "bar"}})
+	_, errs["FindOneAndDeleteCtx"] = users.FindOneAndDeleteCtx(ctx, filter)
+	_, errs["CreateCtx"] = users.CreateCtx(ctx, bson.M{"name": "foo"})
+
+	for name, err := range errs {
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected %s to be canceled, actual %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package gongo
+
+import (
+	"context"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Count counts the documents matching the filter
+func (c *Model) Count(filter interface{}, opts ...*options.CountOptions) (int64, error) {
+	return c.CountCtx(context.Background(), filter, opts...)
+}
+
+// CountCtx counts the documents matching the filter using the provided context
+func (c *Model) CountCtx(ctx context.Context, filter interface{}, opts ...*options.CountOptions) (int64, error) {
+	query, err := c.buildQuery(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+
+	// apply pre-middleware
+	countOpts := options.MergeCountOptions(opts...)
+	q := &QueryContext{
+		Operation: "count",
+		Model:     c,
+		Filter:    *query,
+		Options:   countOpts,
+	}
+	ctx, err = c.applyPreQueryMiddleware(ctx, q)
+	if err != nil {
+		return 0, err
+	}
+
+	count, err := c.Collection().CountDocuments(ctx, q.Filter, countOpts)
+	if err != nil {
+		return 0, c.schema.applyErrorMiddleware(ctx, "count", q.Filter, err)
+	}
+
+	// apply post middleware
+	if err := c.schema.applyPostMiddleware(ctx, "count", q.Filter); err != nil {
+		return count, err
+	}
+	return count, nil
+}
+
+// EstimatedCount returns an estimate of the number of documents in the
+// collection using collection metadata
+func (c *Model) EstimatedCount(opts ...*options.EstimatedDocumentCountOptions) (int64, error) {
+	return c.EstimatedCountCtx(context.Background(), opts...)
+}
+
+// EstimatedCountCtx returns an estimate of the number of documents in the
+// collection using the provided context
+func (c *Model) EstimatedCountCtx(ctx context.Context, opts ...*options.EstimatedDocumentCountOptions) (int64, error) {
+	// apply pre-middleware
+	countOpts := options.MergeEstimatedDocumentCountOptions(opts...)
+	q := &QueryContext{
+		Operation: "estimatedDocumentCount",
+		Model:     c,
+		Filter:    bson.M{},
+		Options:   countOpts,
+	}
+	ctx, err := c.applyPreQueryMiddleware(ctx, q)
+	if err != nil {
+		return 0, err
+	}
+
+	count, err := c.Collection().EstimatedDocumentCount(ctx, countOpts)
+	if err != nil {
+		return 0, c.schema.applyErrorMiddleware(ctx, "estimatedDocumentCount", q.Filter, err)
+	}
+
+	// apply post middleware
+	if err := c.schema.applyPostMiddleware(ctx, "estimatedDocumentCount", q.Filter); err != nil {
+		return count, err
+	}
+	return count, nil
+}
+
+// Exists returns true if at least one document matches the filter
+func (c *Model) Exists(filter interface{}) (bool, error) {
+	return c.ExistsCtx(context.Background(), filter)
+}
+
+// ExistsCtx returns true if at least one document matches the filter using
+// the provided context. the count middleware is applied
+func (c *Model) ExistsCtx(ctx context.Context, filter interface{}) (bool, error) {
+	count, err := c.CountCtx(ctx, filter, options.Count().SetLimit(1))
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// Distinct returns the distinct values of the path for the documents
+// matching the filter
+func (c *Model) Distinct(path string, filter interface{}, opts ...*options.DistinctOptions) ([]interface{}, error) {
+	return c.DistinctCtx(context.Background(), path, filter, opts...)
+}
+
+// DistinctCtx returns the distinct values of the path for the documents matching
+// the filter using the provided context. values are cast to the type of the
+// schema field at the path and virtual paths are resolved with their getters
+func (c *Model) DistinctCtx(ctx context.Context, path string, filter interface{}, opts ...*options.DistinctOptions) ([]interface{}, error) {
+	query, err := c.buildQuery(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	// apply pre-middleware
+	distinctOpts := options.MergeDistinctOptions(opts...)
+	q := &QueryContext{
+		Operation: "distinct",
+		Model:     c,
+		Filter:    *query,
+		Options:   distinctOpts,
+	}
+	ctx, err = c.applyPreQueryMiddleware(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	var values []interface{}
+	fieldPath := strings.Split(path, ".")
+	if config, _, prefix := c.schema.virtualAtPath(fieldPath); config != nil && config.Get != nil {
+		values, err = c.distinctVirtual(ctx, q.Filter, config, prefix)
+	} else {
+		values, err = c.Collection().Distinct(ctx, path, q.Filter, distinctOpts)
+		if err == nil {
+			values = c.castDistinct(fieldPath, values)
+		}
+	}
+	if err != nil {
+		return nil, c.schema.applyErrorMiddleware(ctx, "distinct", q.Filter, err)
+	}
+
+	// apply post middleware
+	if err := c.schema.applyPostMiddleware(ctx, "distinct", q.Filter); err != nil {
+		return values, err
+	}
+	return values, nil
+}
+
+// casts distinct values to the type of the schema field at the path,
+// values that cannot be cast are returned as is
+func (c *Model) castDistinct(fieldPath []string, values []interface{}) []interface{} {
+	field, _ := c.schema.fieldAtPath(fieldPath)
+	if field == nil {
+		return values
+	}
+	cast := make([]interface{}, 0)
+	for _, value := range values {
+		if v, err := castValue(field.elementType, value); err == nil {
+			value = v
+		}
+		cast = append(cast, value)
+	}
+	return cast
+}
+
+// resolves the distinct values of a virtual by applying its getter
+// to each matching document. virtuals on nested schemas are applied
+// to the sub-documents at the prefix
+func (c *Model) distinctVirtual(ctx context.Context, filter bson.M, config *VirtualConfig, prefix []string) ([]interface{}, error) {
+	cur, err := c.Collection().Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	values := make([]interface{}, 0)
+	seen := make(map[string]bool)
+	var getErr error
+	for cur.Next(ctx) {
+		doc := bson.M{}
+		if err := cur.Decode(&doc); err != nil {
+			return nil, err
+		}
+		mapPathValues(doc, prefix, func(value interface{}) interface{} {
+			sub, ok := value.(bson.M)
+			if !ok || getErr != nil {
+				return value
+			}
+			v, err := config.Get(sub)
+			if err != nil {
+				getErr = err
+			} else if key := refKey(v); !seen[key] {
+				seen[key] = true
+				values = append(values, v)
+			}
+			return value
+		})
+		if getErr != nil {
+			return nil, getErr
+		}
+	}
+	if err := cur.Err(); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
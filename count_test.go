@@ -0,0 +1,93 @@
+package gongo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCountMiddleware(t *testing.T) {
+	g := New()
+	schema := Schema{
+		Fields: SchemaFieldMap{
+			"age": {
+				Type: IntType,
+			},
+		},
+	}
+	users, err := g.Model("User", &schema)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	errStop := errors.New("stop")
+	operations := make([]string, 0)
+	for _, op := range []string{"count", "estimatedDocumentCount", "distinct"} {
+		users.schema.Pre(op, func(ctx context.Context, filter bson.M, next NextFunc) error {
+			query, _ := QueryFromContext(ctx)
+			if query.Operation != "estimatedDocumentCount" && filter["age"] != 10 {
+				return errors.New("expected cast filter")
+			}
+			operations = append(operations, query.Operation)
+			return errStop
+		})
+	}
+
+	if _, err := users.Count(bson.M{"age": "10"}); err != errStop {
+		t.Errorf("expected count middleware error, actual %v", err)
+		return
+	}
+	if _, err := users.Exists(bson.M{"age": "10"}); err != errStop {
+		t.Errorf("expected exists middleware error, actual %v", err)
+		return
+	}
+	if _, err := users.EstimatedCount(); err != errStop {
+		t.Errorf("expected estimated count middleware error, actual %v", err)
+		return
+	}
+	if _, err := users.Distinct("age", bson.M{"age": "10"}); err != errStop {
+		t.Errorf("expected distinct middleware error, actual %v", err)
+		return
+	}
+
+	expected := []string{"count", "count", "estimatedDocumentCount", "distinct"}
+	if len(operations) != len(expected) {
+		t.Errorf("expected %v, actual %v", expected, operations)
+	}
+}
+
+func TestCastDistinct(t *testing.T) {
+	g := New()
+	schema := Schema{
+		Fields: SchemaFieldMap{
+			"born": {
+				Type: DateType,
+			},
+			"tags": {
+				Type: []interface{}{StringType},
+			},
+		},
+	}
+	users, err := g.Model("User", &schema)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	dates := users.castDistinct([]string{"born"}, []interface{}{primitive.NewDateTimeFromTime(now)})
+	if actual, ok := dates[0].(time.Time); !ok || !actual.Equal(now) {
+		t.Errorf("expected %v, actual %v", now, dates[0])
+		return
+	}
+
+	tags := users.castDistinct([]string{"tags"}, []interface{}{"foo", int32(1)})
+	if tags[0] != "foo" || tags[1] != "1" {
+		t.Errorf("expected string tags, actual %v", tags)
+	}
+}
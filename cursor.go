@@ -0,0 +1,138 @@
+package gongo
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// DocumentCursor iterates over the results of a find without loading
+// the entire result set. documents are only hydrated when requested
+type DocumentCursor struct {
+	ctx    context.Context
+	model  *Model
+	cursor *mongo.Cursor
+	doc    *Document
+	lean   bool
+	err    error
+}
+
+// FindIter finds documents and returns a cursor over the results
+func (c *Model) FindIter(filter interface{}, opts ...*options.FindOptions) (*DocumentCursor, error) {
+	return c.FindIterCtx(context.Background(), filter, opts...)
+}
+
+// FindIterCtx finds documents and returns a cursor over the results using the provided context
+func (c *Model) FindIterCtx(ctx context.Context, filter interface{}, opts ...*options.FindOptions) (*DocumentCursor, error) {
+	query, err := c.buildQuery(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	// apply pre-middleware
+	findOpts := options.MergeFindOptions(opts...)
+	q := &QueryContext{
+		Operation:  "find",
+		Model:      c,
+		Filter:     *query,
+		Projection: findOpts.Projection,
+		Options:    findOpts,
+	}
+	ctx, err = c.applyPreQueryMiddleware(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	findOpts.Projection = q.Projection
+
+	// perform the find operation
+	cur, err := c.Collection().Find(ctx, q.Filter, findOpts)
+	if err != nil {
+		return nil, c.schema.applyErrorMiddleware(ctx, "find", q.Filter, err)
+	}
+	if err := cur.Err(); err != nil {
+		cur.Close(ctx)
+		return nil, c.schema.applyErrorMiddleware(ctx, "find", q.Filter, err)
+	}
+
+	// apply post middleware
+	if err := c.schema.applyPostMiddleware(ctx, "find", q.Filter); err != nil {
+		cur.Close(ctx)
+		return nil, err
+	}
+
+	return &DocumentCursor{
+		ctx:    ctx,
+		model:  c,
+		cursor: cur,
+	}, nil
+}
+
+// SetBatchSize sets the number of documents fetched from the server
+// in each subsequent batch
+func (c *DocumentCursor) SetBatchSize(batchSize int32) {
+	if c.cursor != nil {
+		c.cursor.SetBatchSize(batchSize)
+	}
+}
+
+// Next advances the cursor to the next document, returning false
+// when there are no more documents or an error occurred
+func (c *DocumentCursor) Next(ctx context.Context) bool {
+	c.doc = nil
+	if c.err != nil || c.cursor == nil {
+		return false
+	}
+	if !c.cursor.Next(ctx) {
+		c.err = c.cursor.Err()
+		return false
+	}
+	return true
+}
+
+// Document hydrates and returns the current document
+func (c *DocumentCursor) Document() (*Document, error) {
+	if c.doc != nil {
+		return c.doc, nil
+	} else if c.cursor == nil || c.cursor.Current == nil {
+		return nil, fmt.Errorf("no current document, call Next first")
+	}
+
+	temp := bson.M{}
+	if err := c.cursor.Decode(&temp); err != nil {
+		return nil, err
+	}
+	var doc *Document
+	var err error
+	if c.lean {
+		doc = c.model.newLean(temp)
+	} else if doc, err = c.model.hydrate(c.ctx, temp); err != nil {
+		return nil, err
+	}
+	c.doc = doc
+	return doc, nil
+}
+
+// Decode decodes the current document to the target
+func (c *DocumentCursor) Decode(target interface{}) error {
+	doc, err := c.Document()
+	if err != nil {
+		return err
+	}
+	return doc.Decode(target)
+}
+
+// Err returns the last error encountered by the cursor
+func (c *DocumentCursor) Err() error {
+	return c.err
+}
+
+// Close closes the cursor, closing a cursor that was never opened does nothing
+func (c *DocumentCursor) Close() error {
+	if c.cursor == nil {
+		return nil
+	}
+	return c.cursor.Close(c.ctx)
+}
@@ -0,0 +1,144 @@
+package gongo
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestFindIterMiddleware(t *testing.T) {
+	g := New()
+	schema := Schema{
+		Fields: SchemaFieldMap{
+			"age": {
+				Type: IntType,
+			},
+		},
+	}
+
+	// stop the find before it reaches the server
+	errStop := errors.New("stop")
+	var filter bson.M
+	if err := schema.Pre("find", func(ctx context.Context, query bson.M, next NextFunc) error {
+		filter = query
+		return errStop
+	}); err != nil {
+		t.Error(err)
+		return
+	}
+
+	users, err := g.Model("User", &schema)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	id := primitive.NewObjectID()
+	cur, err := users.FindIter(bson.M{"id": id.Hex(), "age": bson.M{"$gte": "18"}})
+	if err != errStop {
+		t.Errorf("expected find middleware error, actual %v", err)
+		return
+	} else if cur != nil {
+		t.Error("expected no cursor when the find fails")
+		return
+	}
+	expected := bson.M{"_id": id, "age": bson.M{"$gte": 18}}
+	if !reflect.DeepEqual(expected, filter) {
+		t.Errorf("expected %v, actual %v", expected, filter)
+	}
+}
+
+func TestDocumentCursor(t *testing.T) {
+	g := New()
+	schema := Schema{
+		Fields: SchemaFieldMap{
+			"name": {
+				Type: StringType,
+			},
+			"age": {
+				Type: IntType,
+			},
+		},
+	}
+	users, err := g.Model("User", &schema)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	ctx := context.Background()
+
+	// a cursor that was never opened has no documents
+	unopened := &DocumentCursor{ctx: ctx, model: users}
+	unopened.SetBatchSize(10)
+	if unopened.Next(ctx) {
+		t.Error("expected no documents from an unopened cursor")
+		return
+	}
+	if _, err := unopened.Document(); err == nil {
+		t.Error("expected error getting a document from an unopened cursor")
+		return
+	}
+	if err := unopened.Err(); err != nil {
+		t.Errorf("expected no cursor error, actual %v", err)
+		return
+	}
+	if err := unopened.Close(); err != nil {
+		t.Errorf("expected closing an unopened cursor to succeed, actual %v", err)
+		return
+	}
+
+	// documents are hydrated when requested
+	id := primitive.NewObjectID()
+	cursor, err := mongo.NewCursorFromDocuments([]interface{}{
+		bson.M{"_id": id, "name": "foo", "age": int32(42)},
+	}, nil, nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	cur := &DocumentCursor{ctx: ctx, model: users, cursor: cursor}
+	if _, err := cur.Document(); err == nil {
+		t.Error("expected error getting a document before calling Next")
+		return
+	}
+	if !cur.Next(ctx) {
+		t.Errorf("expected a document, actual %v", cur.Err())
+		return
+	}
+	doc, err := cur.Document()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if again, _ := cur.Document(); again != doc {
+		t.Error("expected the current document to be hydrated once")
+		return
+	}
+
+	user := bson.M{}
+	if err := cur.Decode(&user); err != nil {
+		t.Error(err)
+		return
+	}
+	if user["_id"] != id || user["name"] != "foo" || user["age"] != int32(42) {
+		t.Errorf("expected decoded document, actual %v", user)
+		return
+	}
+
+	if cur.Next(ctx) {
+		t.Error("expected no more documents")
+		return
+	}
+	if err := cur.Err(); err != nil {
+		t.Error(err)
+		return
+	}
+	if err := cur.Close(); err != nil {
+		t.Error(err)
+	}
+}
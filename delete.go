@@ -0,0 +1,75 @@
+package gongo
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// DeleteOne deletes the first document matching the filter
+func (c *Model) DeleteOne(filter interface{}, opts ...*options.DeleteOptions) (int64, error) {
+	return c.DeleteOneCtx(context.Background(), filter, opts...)
+}
+
+// DeleteOneCtx deletes the first document matching the filter using the provided context
+func (c *Model) DeleteOneCtx(ctx context.Context, filter interface{}, opts ...*options.DeleteOptions) (int64, error) {
+	return c.deleteWithMiddleware(ctx, "deleteOne", filter, opts...)
+}
+
+// DeleteMany deletes all documents matching the filter
+func (c *Model) DeleteMany(filter interface{}, opts ...*options.DeleteOptions) (int64, error) {
+	return c.DeleteManyCtx(context.Background(), filter, opts...)
+}
+
+// DeleteManyCtx deletes all documents matching the filter using the provided context
+func (c *Model) DeleteManyCtx(ctx context.Context, filter interface{}, opts ...*options.DeleteOptions) (int64, error) {
+	return c.deleteWithMiddleware(ctx, "deleteMany", filter, opts...)
+}
+
+// performs a delete operation wrapped in the operation middleware
+func (c *Model) deleteWithMiddleware(
+	ctx context.Context,
+	operation string,
+	filter interface{},
+	opts ...*options.DeleteOptions,
+) (int64, error) {
+	query, err := c.prepareDelete(ctx, operation, filter)
+	if err != nil {
+		return 0, err
+	}
+
+	// perform the delete
+	var result *mongo.DeleteResult
+	if operation == "deleteMany" {
+		result, err = c.Collection().DeleteMany(ctx, query, opts...)
+	} else {
+		result, err = c.Collection().DeleteOne(ctx, query, opts...)
+	}
+	if err != nil {
+		return 0, c.schema.applyErrorMiddleware(ctx, operation, query, err)
+	}
+	deletedCount := result.DeletedCount
+
+	// apply post middleware
+	if err := c.schema.applyPostMiddleware(ctx, operation, query); err != nil {
+		return deletedCount, err
+	}
+
+	return deletedCount, nil
+}
+
+// builds the query for a delete operation and applies the pre middleware
+func (c *Model) prepareDelete(ctx context.Context, operation string, filter interface{}) (bson.M, error) {
+	query, err := c.buildQuery(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	// apply pre-middleware
+	if err := c.schema.applyPreMiddleware(ctx, operation, *query); err != nil {
+		return nil, err
+	}
+	return *query, nil
+}
@@ -0,0 +1,97 @@
+package gongo
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestDeleteMiddleware(t *testing.T) {
+	g := New()
+	schema := Schema{
+		Fields: SchemaFieldMap{
+			"name": {
+				Type: StringType,
+			},
+			"age": {
+				Type: IntType,
+			},
+		},
+	}
+
+	// stop each delete before it reaches the server
+	errStop := errors.New("stop")
+	filters := map[string]bson.M{}
+	for _, op := range []string{"deleteOne", "deleteMany", "remove"} {
+		operation := op
+		if err := schema.Pre(operation, func(ctx context.Context, query bson.M, next NextFunc) error {
+			filters[operation] = query
+			return errStop
+		}); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	users, err := g.Model("User", &schema)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	// virtual paths are translated
+	id := primitive.NewObjectID()
+	if _, err := users.DeleteOne(bson.M{"id": id.Hex(), "age": 10}); err != errStop {
+		t.Errorf("expected deleteOne middleware error, actual %v", err)
+		return
+	}
+	expected := bson.M{"_id": id, "age": 10}
+	if !reflect.DeepEqual(expected, filters["deleteOne"]) {
+		t.Errorf("expected %v, actual %v", expected, filters["deleteOne"])
+		return
+	}
+
+	if _, err := users.DeleteMany(bson.M{"age": bson.M{"$gt": 10}}); err != errStop {
+		t.Errorf("expected deleteMany middleware error, actual %v", err)
+		return
+	}
+	expected = bson.M{"age": bson.M{"$gt": 10}}
+	if !reflect.DeepEqual(expected, filters["deleteMany"]) {
+		t.Errorf("expected %v, actual %v", expected, filters["deleteMany"])
+		return
+	}
+
+	// remove middleware receives the document
+	user, err := users.New(bson.M{"_id": id, "name": "foo"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if err := user.Remove(); err != errStop {
+		t.Errorf("expected remove middleware error, actual %v", err)
+		return
+	}
+	if doc := filters["remove"]; doc["_id"] != id || doc["name"] != "foo" {
+		t.Errorf("expected remove middleware to receive the document, actual %v", doc)
+		return
+	}
+
+	// documents without an id cannot be removed
+	delete(filters, "remove")
+	unsaved, err := users.New(bson.M{"name": "bar"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if err := unsaved.Remove(); err == nil || err == errStop {
+		t.Errorf("expected unsaved document error, actual %v", err)
+		return
+	}
+	if _, ok := filters["remove"]; ok {
+		t.Error("expected remove middleware to be skipped for unsaved documents")
+	}
+}
@@ -1,7 +1,11 @@
 package gongo
 
 import (
+	"context"
 	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/bhoriuchi/gongo/helpers"
@@ -11,11 +15,15 @@ import (
 
 // Document a mongodb document wrapper
 type Document struct {
-	id    interface{}
-	model *Model
-	prev  *bson.M
-	cur   *bson.M
-	next  *bson.M
+	id        interface{}
+	model     *Model
+	prev      *bson.M
+	cur       *bson.M
+	next      *bson.M
+	modified  map[string]bool
+	populated map[string]map[string]*Document
+	virtuals  map[string]DocumentList
+	counts    map[string]int64
 }
 
 // DocumentList a list of documents
@@ -62,44 +70,123 @@ func (c *Document) Set(path string, value interface{}) error {
 		return fmt.Errorf("undefined path %q cannot be set", path)
 	}
 
+	// keep a copy of the proposed changes so that only
+	// this change is reverted if validation fails
+	previous := cloneDocument(c.next)
 	if _, err := pointerstructure.Set(c.next, p, value); err != nil {
 		return err
 	}
 
-	// validate the changes, if they fail revert to the current document
-	if err := c.Validate(); err != nil {
-		c.revertCurrent()
+	// validate the changes, if they fail revert the change
+	if err := c.validate(); err != nil {
+		c.next = &previous
 		return err
 	}
 
 	return nil
 }
 
-// Validate validates the document proposed changes
+// Unset removes the path from the current proposed changes
+func (c *Document) Unset(path string) error {
+	p := helpers.DotPathToSlashPath(path)
+	fieldPath := strings.Split(path, ".")
+	if !c.model.schema.hasFieldPath(fieldPath) {
+		return fmt.Errorf("undefined path %q cannot be unset", path)
+	}
+
+	pointer, err := pointerstructure.Parse(p)
+	if err != nil {
+		return err
+	}
+	previous := cloneDocument(c.next)
+	if _, err := pointer.Delete(c.next); err != nil {
+		return err
+	}
+
+	// validate the changes, if they fail revert the change
+	if err := c.validate(); err != nil {
+		c.next = &previous
+		return err
+	}
+
+	return nil
+}
+
+// MarkModified marks a path as modified so that it is included
+// in the next save even if its value has not changed
+func (c *Document) MarkModified(path string) {
+	if c.modified == nil {
+		c.modified = make(map[string]bool)
+	}
+	c.modified[path] = true
+}
+
+// IsModified returns true if the path, one of its parents, or one
+// of its children has been modified since the last save
+func (c *Document) IsModified(path string) bool {
+	for _, p := range c.ModifiedPaths() {
+		if p == path ||
+			strings.HasPrefix(p, path+".") ||
+			strings.HasPrefix(path, p+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// ModifiedPaths returns a sorted list of paths that have been
+// modified since the last save
+func (c *Document) ModifiedPaths() []string {
+	set, unset := diffDocuments([]string{}, cloneDocument(c.cur), cloneDocument(c.next))
+	paths := append(set, unset...)
+	for path := range c.modified {
+		paths = append(paths, path)
+	}
+	return uniquePaths(paths)
+}
+
+// Validate validates the document proposed changes, validation failures
+// are returned as a *ValidationError
 func (c *Document) Validate() error {
-	if _, err := c.model.schema.walk(c.next, []string{}, &walkOptions{
+	return c.ValidateCtx(context.Background())
+}
+
+// ValidateCtx validates the document using the provided context
+func (c *Document) ValidateCtx(ctx context.Context) error {
+	// apply pre-middleware
+	if err := c.model.schema.applyPreMiddleware(ctx, "validate", *c.next); err != nil {
+		return err
+	}
+
+	if err := c.validate(); err != nil {
+		return c.model.schema.applyErrorMiddleware(ctx, "validate", *c.next, err)
+	}
+
+	// apply post middleware
+	return c.model.schema.applyPostMiddleware(ctx, "validate", cloneDocument(c.next))
+}
+
+// validates the proposed changes without running the validate middleware,
+// changes made with Set and Unset are checked as they are made while the
+// middleware only runs when the whole document is validated or saved
+func (c *Document) validate() error {
+	_, err := c.model.schema.walk(c.next, []string{}, &walkOptions{
 		applySetters:     false,
 		applyDefaults:    false,
-		castObjectID:     false,
+		castTypes:        false,
 		validateTypes:    true,
 		validateCustom:   true,
 		validateRequired: true,
-	}); err != nil {
-		return err
-	}
-	return nil
+	})
+	return err
 }
 
 // loads document data
 func (c *Document) load(document interface{}, schema *Schema) error {
-	// first conver to a map
-	prev := bson.M{}
-	next := bson.M{}
-
 	cur, err := c.model.schema.walk(document, []string{}, &walkOptions{
 		applySetters:     true,
 		applyDefaults:    false,
-		castObjectID:     true,
+		castTypes:        true,
 		validateTypes:    true,
 		validateCustom:   false,
 		validateRequired: false,
@@ -110,21 +197,16 @@ func (c *Document) load(document interface{}, schema *Schema) error {
 	}
 
 	// get the id
-	doc := *c.cur
-	if id, ok := doc["_id"]; ok {
+	if id, ok := (*cur)["_id"]; ok {
 		c.id = id
 	}
 
-	c.cur = cur
-
 	// copy cur to prev and next
-	if err := c.model.gongo.weakDecode(cur, &prev); err != nil {
-		return err
-	}
-	if err := c.model.gongo.weakDecode(cur, &next); err != nil {
-		return err
-	}
+	current := cloneDocument(cur)
+	prev := cloneDocument(cur)
+	next := cloneDocument(cur)
 
+	c.cur = &current
 	c.prev = &prev
 	c.next = &next
 	return nil
@@ -132,22 +214,18 @@ func (c *Document) load(document interface{}, schema *Schema) error {
 
 // moves current to prev, next to cur, and leaves next alone
 func (c *Document) moveNext() error {
-	if err := c.model.gongo.weakDecode(c.cur, c.prev); err != nil {
-		return err
-	}
-	if err := c.model.gongo.weakDecode(c.next, c.cur); err != nil {
-		return err
-	}
+	prev := cloneDocument(c.cur)
+	cur := cloneDocument(c.next)
+	c.prev = &prev
+	c.cur = &cur
+	c.modified = nil
 	return nil
 }
 
 // reverts the next to the current essentially removing any updates on the model
 // this does not save the revert
 func (c *Document) revertCurrent() error {
-	next := bson.M{}
-	if err := c.model.gongo.weakDecode(c.cur, &next); err != nil {
-		return err
-	}
+	next := cloneDocument(c.cur)
 	c.next = &next
 	return nil
 }
@@ -155,17 +233,9 @@ func (c *Document) revertCurrent() error {
 // reverts the model to the previous version of the data
 // this does not dave the revert
 func (c *Document) revertPrevious() error {
-	original := c.cur
-	cur := bson.M{}
-	if err := c.model.gongo.weakDecode(c.prev, &cur); err != nil {
-		return err
-	}
-	if err := c.revertCurrent(); err != nil {
-		c.cur = original
-		return err
-	}
+	cur := cloneDocument(c.prev)
 	c.cur = &cur
-	return nil
+	return c.revertCurrent()
 }
 
 // Decode decodes the document to an interface
@@ -175,10 +245,7 @@ func (c *Document) Decode(target interface{}) error {
 	}
 
 	// make a working copy
-	doc := bson.M{}
-	if err := c.model.gongo.weakDecode(c.cur, &doc); err != nil {
-		return err
-	}
+	doc := cloneDocument(c.cur)
 
 	// add the id to the document
 	if c.id != nil {
@@ -190,77 +257,146 @@ func (c *Document) Decode(target interface{}) error {
 		return err
 	}
 
+	// replace references with their populated documents
+	for path, refs := range c.populated {
+		var decodeErr error
+		mapPathValues(doc, strings.Split(path, "."), func(value interface{}) interface{} {
+			ref, ok := refs[refKey(value)]
+			if !ok {
+				return nil
+			}
+			m := bson.M{}
+			if err := ref.Decode(&m); err != nil {
+				decodeErr = err
+			}
+			return m
+		})
+		if decodeErr != nil {
+			return decodeErr
+		}
+	}
+
+	// add populated virtuals
+	for name, count := range c.counts {
+		doc[name] = count
+	}
+	for name, list := range c.virtuals {
+		config := (*c.model.schema.Virtuals)[name]
+		decoded := make([]interface{}, 0)
+		for _, ref := range list {
+			m := bson.M{}
+			if err := ref.Decode(&m); err != nil {
+				return err
+			}
+			decoded = append(decoded, m)
+		}
+		if !config.JustOne {
+			doc[name] = decoded
+		} else if len(decoded) > 0 {
+			doc[name] = decoded[0]
+		} else {
+			doc[name] = nil
+		}
+	}
+
 	// decode the structure
 	return c.model.gongo.weakDecode(doc, target)
 }
 
 // Save saves a document
 func (c *Document) Save(timeout ...*int) error {
-	// re-usable error handler
-	errorFunc := func(doc bson.M, err error) error {
-		// apply post middleware
-		if err := c.model.schema.applyPostMiddleware("save", doc, err); err != nil {
-			return err
-		}
-		return err
-	}
+	ctx, cancelFunc := newContext(timeout...)
+	defer cancelFunc()
+	return c.SaveCtx(ctx)
+}
+
+// SaveCtx saves a document using the provided context
+func (c *Document) SaveCtx(ctx context.Context) error {
+	// inside a transaction the document is restored if the attempt is aborted
+	snapshotDocument(ctx, c)
 
 	// create a working documnet
-	doc := &bson.M{}
-	if err := c.model.gongo.weakDecode(c.next, doc); err != nil {
-		return err
+	working := cloneDocument(c.next)
+	doc := &working
+
+	// re-usable error handler
+	errorFunc := func(err error) error {
+		return c.model.schema.applyErrorMiddleware(ctx, "save", *doc, err)
 	}
 
 	// apply pre-middleware
-	if err := c.model.schema.applyPreMiddleware("save", *doc); err != nil {
+	if err := c.model.schema.applyPreMiddleware(ctx, "save", *doc); err != nil {
 		return err
 	}
 
+	// new documents are stamped before validation
+	if c.id == nil {
+		c.model.schema.setTimestamps(*doc, true)
+	}
+
 	// walk document with full validation
+	if err := c.model.schema.applyPreMiddleware(ctx, "validate", *doc); err != nil {
+		return err
+	}
 	document, err := c.model.schema.walk(doc, []string{}, &walkOptions{
 		applySetters:     true,
 		applyDefaults:    true,
-		castObjectID:     true,
+		castTypes:        true,
 		validateTypes:    true,
 		validateCustom:   true,
 		validateRequired: true,
 	})
 
+	// validation errors pass through the validate then the save error middleware
 	if err != nil {
+		return errorFunc(c.model.schema.applyErrorMiddleware(ctx, "validate", *doc, err))
+	}
+	if err := c.model.schema.applyPostMiddleware(ctx, "validate", cloneDocument(document)); err != nil {
 		return err
 	}
 
-	// create a context
-	ctx, cancelFunc := newContext(timeout...)
-	defer cancelFunc()
-
-	// save
+	// save, existing documents only send the modified paths
+	// and skip the update entirely when nothing has changed
+	versionKey := c.model.schema.Options.versionKey()
 	if c.id != nil {
-		result, err := c.model.Collection().UpdateOne(
-			ctx,
-			bson.M{"_id": c.id},
-			bson.M{"$set": document},
-		)
-		if err != nil {
-			return errorFunc(nil, err)
-		} else if result.MatchedCount < 1 {
-			return errorFunc(nil, fmt.Errorf("failed to update %s", c.id))
+		if filter, update, version := c.buildSave(*document); len(update) > 0 {
+			result, err := c.model.Collection().UpdateOne(
+				ctx,
+				filter,
+				update,
+			)
+			if err != nil {
+				return errorFunc(err)
+			} else if result.MatchedCount < 1 {
+				if versionKey != "" {
+					return errorFunc(&VersionConflictError{ID: c.id, Version: version})
+				}
+				return errorFunc(fmt.Errorf("failed to update %s", c.id))
+			}
+
+			if versionKey != "" {
+				(*document)[versionKey] = nextVersion(version)
+			}
 		}
 	} else {
+		c.model.schema.setVersion(*document)
 		result, err := c.model.Collection().InsertOne(
 			ctx,
-			doc,
+			document,
 		)
 		if err != nil {
-			return errorFunc(nil, err)
+			return errorFunc(err)
 		} else if result.InsertedID == nil {
-			return errorFunc(nil, fmt.Errorf("insert failed, no ObjectId returned"))
+			return errorFunc(fmt.Errorf("insert failed, no ObjectId returned"))
 		}
 		c.id = result.InsertedID
 	}
 
-	// apply post middleware
-	if err := c.model.schema.applyPostMiddleware("save", *document, nil); err != nil {
+	// apply post middleware, inside a transaction it is deferred until commit
+	saved := cloneDocument(document)
+	if err := afterCommit(ctx, func(ctx context.Context) error {
+		return c.model.schema.applyPostMiddleware(ctx, "save", saved)
+	}); err != nil {
 		return err
 	}
 
@@ -269,3 +405,279 @@ func (c *Document) Save(timeout ...*int) error {
 	c.next = &nextDoc
 	return c.moveNext()
 }
+
+// builds the filter and update used to save an existing document along
+// with the version the update expects. the update is empty when nothing
+// has changed. the filter includes the current version and the update
+// increments it so that concurrent saves are detected
+func (c *Document) buildSave(document bson.M) (bson.M, bson.M, interface{}) {
+	update := c.buildUpdate(document)
+	if len(update) == 0 {
+		return nil, update, nil
+	}
+	filter := bson.M{"_id": c.id}
+
+	var version interface{}
+	if versionKey := c.model.schema.Options.versionKey(); versionKey != "" {
+		if v, ok := (*c.cur)[versionKey]; ok && v != nil {
+			version = v
+			filter[versionKey] = v
+		} else {
+			filter[versionKey] = bson.M{"$exists": false}
+		}
+		update["$inc"] = bson.M{versionKey: 1}
+	}
+
+	// only stamp the update time when there are changes
+	if timestamps := c.model.schema.Options.Timestamps; timestamps != nil {
+		set, _ := update["$set"].(bson.M)
+		if set == nil {
+			set = bson.M{}
+			update["$set"] = set
+		}
+		c.model.schema.setTimestamps(set, false)
+		document[timestamps.updatedAtKey()] = set[timestamps.updatedAtKey()]
+	}
+	return filter, update, version
+}
+
+// builds a minimal update document containing $set for changed
+// paths and $unset for removed paths
+func (c *Document) buildUpdate(document bson.M) bson.M {
+	update := bson.M{}
+	next := cloneDocument(&document)
+	setPaths, unsetPaths := diffDocuments([]string{}, cloneDocument(c.cur), next)
+
+	// include paths explicitly marked as modified
+	for path := range c.modified {
+		if _, ok := getPathValue(next, strings.Split(path, ".")); ok {
+			setPaths = append(setPaths, path)
+		} else {
+			unsetPaths = append(unsetPaths, path)
+		}
+	}
+
+	// the version key is managed by save
+	versionKey := c.model.schema.Options.versionKey()
+
+	set := bson.M{}
+	for _, path := range uniquePaths(setPaths) {
+		if path == "_id" || path == versionKey {
+			continue
+		}
+		value, _ := getPathValue(next, strings.Split(path, "."))
+		set[path] = value
+	}
+	// paths under a set path are replaced along with it and
+	// would conflict with the $set if they were also unset
+	unset := bson.M{}
+	for _, path := range uniquePaths(unsetPaths) {
+		if !hasPathPrefix(path, set) && path != "_id" && path != versionKey {
+			unset[path] = ""
+		}
+	}
+
+	if len(set) > 0 {
+		update["$set"] = set
+	}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	return update
+}
+
+// returns the version following the current one
+func nextVersion(version interface{}) int64 {
+	if v, err := castLong(version); err == nil {
+		return v.(int64) + 1
+	}
+	return 1
+}
+
+// compares two documents and returns the paths that were
+// set or changed and the paths that were removed
+func diffDocuments(path []string, cur, next bson.M) ([]string, []string) {
+	set := make([]string, 0)
+	unset := make([]string, 0)
+
+	for key, nextValue := range next {
+		keyPath := childPath(path, key)
+		curValue, ok := cur[key]
+		if !ok {
+			set = append(set, strings.Join(keyPath, "."))
+			continue
+		}
+
+		// recurse into sub-documents
+		curDoc, curIsDoc := curValue.(bson.M)
+		nextDoc, nextIsDoc := nextValue.(bson.M)
+		if curIsDoc && nextIsDoc {
+			s, u := diffDocuments(keyPath, curDoc, nextDoc)
+			set = append(set, s...)
+			unset = append(unset, u...)
+			continue
+		}
+
+		if !reflect.DeepEqual(curValue, nextValue) {
+			set = append(set, strings.Join(keyPath, "."))
+		}
+	}
+
+	for key := range cur {
+		if _, ok := next[key]; !ok {
+			unset = append(unset, strings.Join(childPath(path, key), "."))
+		}
+	}
+
+	return set, unset
+}
+
+// sorts and removes duplicate paths as well as paths
+// whose parent path is already in the list
+func uniquePaths(paths []string) []string {
+	sort.Strings(paths)
+	result := make([]string, 0)
+	for _, path := range paths {
+		if n := len(result); n > 0 {
+			last := result[n-1]
+			if path == last || strings.HasPrefix(path, last+".") {
+				continue
+			}
+		}
+		result = append(result, path)
+	}
+	return result
+}
+
+// returns true if the path or one of its ancestors is in paths
+func hasPathPrefix(path string, paths bson.M) bool {
+	parts := strings.Split(path, ".")
+	for i := range parts {
+		if _, ok := paths[strings.Join(parts[:i+1], ".")]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// gets the value at the path, array elements are addressed by index
+func getPathValue(doc bson.M, path []string) (interface{}, bool) {
+	var current interface{} = doc
+	for _, key := range path {
+		switch v := current.(type) {
+		case bson.M:
+			value, ok := v[key]
+			if !ok {
+				return nil, false
+			}
+			current = value
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, false
+			}
+			current = v[i]
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// calls fn with each value found at the path replacing the value with
+// the result. arrays are traversed at every level of the path
+func mapPathValues(value interface{}, path []string, fn func(value interface{}) interface{}) interface{} {
+	switch v := value.(type) {
+	case []interface{}:
+		for i, item := range v {
+			v[i] = mapPathValues(item, path, fn)
+		}
+		return v
+	case bson.M:
+		if len(path) > 0 {
+			if child, ok := v[path[0]]; ok {
+				v[path[0]] = mapPathValues(child, path[1:], fn)
+			}
+			return v
+		}
+	}
+	if len(path) == 0 && value != nil {
+		return fn(value)
+	}
+	return value
+}
+
+// creates a deep copy of a document, sub-documents and arrays
+// are copied and converted to bson.M and []interface{}
+func cloneDocument(doc *bson.M) bson.M {
+	if doc == nil {
+		return bson.M{}
+	}
+	return cloneValue(*doc).(bson.M)
+}
+
+// creates a deep copy of a value
+func cloneValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case *bson.M:
+		if v == nil {
+			return nil
+		}
+		return cloneValue(*v)
+	case bson.M:
+		m := bson.M{}
+		for key, val := range v {
+			m[key] = cloneValue(val)
+		}
+		return m
+	case map[string]interface{}:
+		return cloneValue(bson.M(v))
+	case *[]interface{}:
+		if v == nil {
+			return nil
+		}
+		return cloneValue(*v)
+	case bson.A:
+		return cloneValue([]interface{}(v))
+	case []interface{}:
+		a := make([]interface{}, len(v))
+		for i, val := range v {
+			a[i] = cloneValue(val)
+		}
+		return a
+	}
+	return value
+}
+
+// Remove removes the document from the database
+func (c *Document) Remove() error {
+	return c.RemoveCtx(context.Background())
+}
+
+// RemoveCtx removes the document from the database using the provided context
+func (c *Document) RemoveCtx(ctx context.Context) error {
+	if c.id == nil {
+		return fmt.Errorf("cannot remove a document that has not been saved")
+	}
+
+	// apply pre-middleware to a working copy
+	doc := cloneDocument(c.cur)
+	if err := c.model.schema.applyPreMiddleware(ctx, "remove", doc); err != nil {
+		return err
+	}
+
+	query, err := c.model.applyVirtualQueryDocument(&bson.M{"_id": c.id})
+	if err != nil {
+		return err
+	}
+
+	result, err := c.model.Collection().DeleteOne(ctx, query)
+	if err != nil {
+		return c.model.schema.applyErrorMiddleware(ctx, "remove", doc, err)
+	} else if result.DeletedCount < 1 {
+		return c.model.schema.applyErrorMiddleware(ctx, "remove", doc, fmt.Errorf("failed to remove %s", c.id))
+	}
+
+	// apply post middleware
+	return c.model.schema.applyPostMiddleware(ctx, "remove", doc)
+}
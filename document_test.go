@@ -0,0 +1,620 @@
+package gongo
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestDocumentModifiedPaths(t *testing.T) {
+	g := New()
+	barSchema := Schema{
+		Fields: SchemaFieldMap{
+			"name": {
+				Type: StringType,
+			},
+			"size": {
+				Type: IntType,
+			},
+		},
+	}
+	fooSchema := Schema{
+		Fields: SchemaFieldMap{
+			"name": {
+				Type:     StringType,
+				Required: true,
+			},
+			"description": {
+				Type: StringType,
+			},
+			"tags": {
+				Type: []interface{}{StringType},
+			},
+			"bar": {
+				Type: barSchema,
+			},
+		},
+	}
+
+	foo, err := g.Model("Foo", &fooSchema)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	doc, err := foo.New(bson.M{
+		"_id":         primitive.NewObjectID(),
+		"name":        "foo",
+		"description": "bar",
+		"tags":        []interface{}{"a"},
+		"bar": bson.M{
+			"name": "baz",
+			"size": 1,
+		},
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if paths := doc.ModifiedPaths(); len(paths) != 0 {
+		t.Errorf("expected no modified paths, actual %v", paths)
+		return
+	}
+
+	if err := doc.Set("bar.size", 2); err != nil {
+		t.Error(err)
+		return
+	}
+	if err := doc.Unset("description"); err != nil {
+		t.Error(err)
+		return
+	}
+	if err := doc.Unset("name"); err == nil {
+		t.Errorf("expected required path unset to fail")
+		return
+	}
+	doc.MarkModified("tags")
+
+	expected := []string{"bar.size", "description", "tags"}
+	if actual := doc.ModifiedPaths(); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected %v, actual %v", expected, actual)
+		return
+	}
+	if !doc.IsModified("bar") || doc.IsModified("name") {
+		t.Errorf("unexpected IsModified result")
+		return
+	}
+
+	document := cloneDocument(doc.next)
+	expectedUpdate := bson.M{
+		"$set": bson.M{
+			"bar.size": 2,
+			"tags":     []interface{}{"a"},
+		},
+		"$unset": bson.M{
+			"description": "",
+		},
+	}
+	if actual := doc.buildUpdate(document); !reflect.DeepEqual(expectedUpdate, actual) {
+		t.Errorf("expected %v, actual %v", expectedUpdate, actual)
+		return
+	}
+
+	// a modified parent replaces its children so unset
+	// children must not conflict with the parent $set
+	doc.MarkModified("bar")
+	if err := doc.Unset("bar.name"); err != nil {
+		t.Error(err)
+		return
+	}
+	document = cloneDocument(doc.next)
+	bar, _ := getPathValue(document, []string{"bar"})
+	expectedUpdate = bson.M{
+		"$set": bson.M{
+			"bar":  bar,
+			"tags": []interface{}{"a"},
+		},
+		"$unset": bson.M{
+			"description": "",
+		},
+	}
+	if actual := doc.buildUpdate(document); !reflect.DeepEqual(expectedUpdate, actual) {
+		t.Errorf("expected %v, actual %v", expectedUpdate, actual)
+		return
+	}
+	if _, ok := bar.(bson.M)["name"]; ok {
+		t.Errorf("expected bar.name to be removed, actual %v", bar)
+		return
+	}
+}
+
+func TestDocumentVersionKey(t *testing.T) {
+	g := New()
+	fooSchema := Schema{
+		Fields: SchemaFieldMap{
+			"name": {
+				Type: StringType,
+			},
+		},
+	}
+
+	foo, err := g.Model("Foo", &fooSchema)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if field, ok := foo.schema.Fields[DefaultVersionKey]; !ok || field.Type != IntType {
+		t.Errorf("expected %q to be registered as a %s field", DefaultVersionKey, IntType)
+		return
+	}
+
+	// new documents start at version 0
+	inserted := bson.M{"name": "foo"}
+	foo.schema.setVersion(inserted)
+	if v := inserted[DefaultVersionKey]; v != 0 {
+		t.Errorf("expected version 0 on insert, actual %v", v)
+		return
+	}
+
+	// existing documents filter on the current version and increment it
+	id := primitive.NewObjectID()
+	doc, err := foo.New(bson.M{"_id": id, "name": "foo", DefaultVersionKey: 3})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if err := doc.Set("name", "bar"); err != nil {
+		t.Error(err)
+		return
+	}
+	if err := doc.Set(DefaultVersionKey, 10); err != nil {
+		t.Error(err)
+		return
+	}
+	current := (*doc.cur)[DefaultVersionKey]
+	filter, update, version := doc.buildSave(cloneDocument(doc.next))
+	expectedFilter := bson.M{"_id": id, DefaultVersionKey: current}
+	expectedUpdate := bson.M{
+		"$set": bson.M{"name": "bar"},
+		"$inc": bson.M{DefaultVersionKey: 1},
+	}
+	if !reflect.DeepEqual(expectedFilter, filter) {
+		t.Errorf("expected filter %v, actual %v", expectedFilter, filter)
+		return
+	} else if !reflect.DeepEqual(expectedUpdate, update) {
+		t.Errorf("expected update %v, actual %v", expectedUpdate, update)
+		return
+	} else if version != current || nextVersion(version) != 4 {
+		t.Errorf("expected version %v, actual %v", current, version)
+		return
+	}
+
+	// documents saved before versioning was enabled have no version
+	doc, err = foo.New(bson.M{"_id": id, "name": "foo"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if err := doc.Set("name", "bar"); err != nil {
+		t.Error(err)
+		return
+	}
+	filter, _, version = doc.buildSave(cloneDocument(doc.next))
+	expectedFilter = bson.M{"_id": id, DefaultVersionKey: bson.M{"$exists": false}}
+	if !reflect.DeepEqual(expectedFilter, filter) || version != nil {
+		t.Errorf("expected filter %v, actual %v", expectedFilter, filter)
+		return
+	}
+
+	// unchanged documents are not saved
+	doc, err = foo.New(bson.M{"_id": id, "name": "foo", DefaultVersionKey: 3})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if _, update, _ := doc.buildSave(cloneDocument(doc.next)); len(update) != 0 {
+		t.Errorf("expected no update, actual %v", update)
+		return
+	}
+
+	// versioning can be turned off
+	barSchema := Schema{
+		Fields: SchemaFieldMap{
+			"name": {
+				Type: StringType,
+			},
+		},
+		Options: &SchemaOptions{
+			DontVersion: true,
+		},
+	}
+	bar, err := g.Model("Bar", &barSchema)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if _, ok := bar.schema.Fields[DefaultVersionKey]; ok {
+		t.Errorf("expected %q not to be registered", DefaultVersionKey)
+		return
+	}
+	inserted = bson.M{"name": "bar"}
+	bar.schema.setVersion(inserted)
+	if _, ok := inserted[DefaultVersionKey]; ok {
+		t.Errorf("expected no version on insert, actual %v", inserted)
+		return
+	}
+	doc, err = bar.New(bson.M{"_id": id, "name": "bar"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if err := doc.Set("name", "baz"); err != nil {
+		t.Error(err)
+		return
+	}
+	filter, update, _ = doc.buildSave(cloneDocument(doc.next))
+	expectedFilter = bson.M{"_id": id}
+	expectedUpdate = bson.M{"$set": bson.M{"name": "baz"}}
+	if !reflect.DeepEqual(expectedFilter, filter) {
+		t.Errorf("expected filter %v, actual %v", expectedFilter, filter)
+		return
+	} else if !reflect.DeepEqual(expectedUpdate, update) {
+		t.Errorf("expected update %v, actual %v", expectedUpdate, update)
+		return
+	}
+}
+
+func TestDocumentTimestamps(t *testing.T) {
+	g := New()
+	now := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	fooSchema := Schema{
+		Fields: SchemaFieldMap{
+			"name": {
+				Type: StringType,
+			},
+		},
+		Options: &SchemaOptions{
+			Timestamps: &TimestampsOptions{
+				UpdatedAt: "modifiedAt",
+				Now:       func() time.Time { return now },
+			},
+		},
+	}
+
+	foo, err := g.Model("Foo", &fooSchema)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	for _, key := range []string{"createdAt", "modifiedAt"} {
+		if field, ok := foo.schema.Fields[key]; !ok || field.Type != DateType {
+			t.Errorf("expected %q to be registered as a %s field", key, DateType)
+			return
+		}
+	}
+
+	doc := bson.M{"name": "foo"}
+	foo.schema.setTimestamps(doc, true)
+	expected := bson.M{"name": "foo", "createdAt": now, "modifiedAt": now}
+	if !reflect.DeepEqual(expected, doc) {
+		t.Errorf("expected %v, actual %v", expected, doc)
+		return
+	}
+
+	update := bson.M{"$set": bson.M{"name": "bar"}}
+	foo.schema.setUpdateTimestamps(update)
+	expected = bson.M{
+		"$set":         bson.M{"name": "bar", "modifiedAt": now},
+		"$setOnInsert": bson.M{"createdAt": now},
+	}
+	if !reflect.DeepEqual(expected, update) {
+		t.Errorf("expected %v, actual %v", expected, update)
+		return
+	}
+}
+
+func TestDocumentDecodePopulated(t *testing.T) {
+	g := New()
+	userSchema := Schema{
+		Fields: SchemaFieldMap{
+			"name": {
+				Type: StringType,
+			},
+		},
+	}
+	postSchema := Schema{
+		Fields: SchemaFieldMap{
+			"author": {
+				Type: ObjectIDType,
+				Ref:  "User",
+			},
+		},
+	}
+
+	users, err := g.Model("User", &userSchema)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	posts, err := g.Model("Post", &postSchema)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	userID := primitive.NewObjectID()
+	user, err := users.New(bson.M{"_id": userID, "name": "foo"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	post, err := posts.New(bson.M{"_id": primitive.NewObjectID(), "author": userID.Hex()})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	post.populated = map[string]map[string]*Document{
+		"author": {refKey(userID): user},
+	}
+
+	if list := post.Populated("author"); len(list) != 1 || list[0] != user {
+		t.Errorf("expected populated user, actual %v", list)
+		return
+	}
+
+	var actual struct {
+		Author struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		} `json:"author"`
+	}
+	if err := post.Decode(&actual); err != nil {
+		t.Error(err)
+		return
+	}
+	if actual.Author.ID != userID.Hex() || actual.Author.Name != "foo" {
+		t.Errorf("expected populated author, actual %v", actual.Author)
+		return
+	}
+
+	post.Depopulate("author")
+	if list := post.Populated("author"); len(list) != 0 {
+		t.Errorf("expected no populated documents, actual %v", list)
+		return
+	}
+}
+
+func TestDocumentPopulateSanitized(t *testing.T) {
+	g := New(&Options{SanitizeFilter: true})
+	userSchema := Schema{
+		Fields: SchemaFieldMap{
+			"name": {
+				Type: StringType,
+			},
+		},
+	}
+	userSchema.Virtual(&VirtualConfig{
+		Name:         "posts",
+		Ref:          "Post",
+		LocalField:   "_id",
+		ForeignField: "author",
+	})
+	postSchema := Schema{
+		Fields: SchemaFieldMap{
+			"author": {
+				Type: ObjectIDType,
+				Ref:  "User",
+			},
+		},
+	}
+
+	// capture the populate queries and stop them before they reach the server
+	errStop := errors.New("stop")
+	var filter bson.M
+	capture := func(ctx context.Context, query bson.M, next NextFunc) error {
+		filter = query
+		return errStop
+	}
+	for _, schema := range []*Schema{&userSchema, &postSchema} {
+		if err := schema.Pre("find", capture); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	users, err := g.Model("User", &userSchema)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	posts, err := g.Model("Post", &postSchema)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	userID := primitive.NewObjectID()
+	user, err := users.New(bson.M{"_id": userID, "name": "foo"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	post, err := posts.New(bson.M{"_id": primitive.NewObjectID(), "author": userID})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err := post.Populate("author"); !errors.Is(err, errStop) {
+		t.Errorf("expected populate to query the referenced model, actual %v", err)
+		return
+	}
+	expected := bson.M{"_id": bson.M{"$in": []interface{}{userID}}}
+	if !reflect.DeepEqual(expected, filter) {
+		t.Errorf("expected %v, actual %v", expected, filter)
+		return
+	}
+
+	if err := user.Populate("posts"); !errors.Is(err, errStop) {
+		t.Errorf("expected populate to query the referenced model, actual %v", err)
+		return
+	}
+	expected = bson.M{"author": bson.M{"$in": []interface{}{userID}}}
+	if !reflect.DeepEqual(expected, filter) {
+		t.Errorf("expected %v, actual %v", expected, filter)
+		return
+	}
+}
+
+func TestDocumentPopulateVirtualCount(t *testing.T) {
+	g := New()
+	authorSchema := Schema{
+		Fields: SchemaFieldMap{
+			"name": {
+				Type: StringType,
+			},
+		},
+	}
+	authorSchema.Virtual(&VirtualConfig{
+		Name:         "postCount",
+		Ref:          "Post",
+		LocalField:   "_id",
+		ForeignField: "author",
+		Count:        true,
+	})
+	postSchema := Schema{
+		Fields: SchemaFieldMap{
+			"author": {
+				Type: ObjectIDType,
+			},
+		},
+	}
+
+	// counts are made without loading the related documents
+	errStop := errors.New("stop")
+	found := false
+	var filter bson.M
+	postSchema.Pre("find", func(ctx context.Context, query bson.M, next NextFunc) error {
+		found = true
+		return errStop
+	})
+	postSchema.Pre("count", func(ctx context.Context, query bson.M, next NextFunc) error {
+		filter = query
+		return errStop
+	})
+
+	authors, err := g.Model("Author", &authorSchema)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if _, err := g.Model("Post", &postSchema); err != nil {
+		t.Error(err)
+		return
+	}
+
+	authorID := primitive.NewObjectID()
+	author, err := authors.New(bson.M{"_id": authorID, "name": "foo"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if err := author.Populate("postCount"); !errors.Is(err, errStop) {
+		t.Errorf("expected populate to count the related documents, actual %v", err)
+		return
+	}
+	expected := bson.M{"author": bson.M{"$in": []interface{}{authorID}}}
+	if found || !reflect.DeepEqual(expected, filter) {
+		t.Errorf("expected count with %v, actual %v", expected, filter)
+	}
+}
+
+func TestDocumentDecodeVirtualPopulated(t *testing.T) {
+	g := New()
+	authorSchema := Schema{
+		Fields: SchemaFieldMap{
+			"name": {
+				Type: StringType,
+			},
+		},
+	}
+	authorSchema.Virtual(&VirtualConfig{
+		Name:         "posts",
+		Ref:          "Post",
+		LocalField:   "_id",
+		ForeignField: "author",
+	}).Virtual(&VirtualConfig{
+		Name:         "postCount",
+		Ref:          "Post",
+		LocalField:   "_id",
+		ForeignField: "author",
+		Count:        true,
+	})
+	postSchema := Schema{
+		Fields: SchemaFieldMap{
+			"title": {
+				Type: StringType,
+			},
+			"author": {
+				Type: ObjectIDType,
+			},
+		},
+	}
+
+	authors, err := g.Model("Author", &authorSchema)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	posts, err := g.Model("Post", &postSchema)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	authorID := primitive.NewObjectID()
+	author, err := authors.New(bson.M{"_id": authorID, "name": "foo", "posts": "ignored"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	post, err := posts.New(bson.M{"_id": primitive.NewObjectID(), "title": "bar", "author": authorID})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	author.virtuals = map[string]DocumentList{
+		"posts": {post},
+	}
+	author.counts = map[string]int64{
+		"postCount": 1,
+	}
+
+	var actual struct {
+		Name      string `json:"name"`
+		PostCount int    `json:"postCount"`
+		Posts     []struct {
+			Title string `json:"title"`
+		} `json:"posts"`
+	}
+	if err := author.Decode(&actual); err != nil {
+		t.Error(err)
+		return
+	}
+	if actual.PostCount != 1 || len(actual.Posts) != 1 || actual.Posts[0].Title != "bar" {
+		t.Errorf("expected populated posts, actual %v", actual)
+		return
+	}
+
+	author.Depopulate("posts")
+	if list := author.Populated("posts"); len(list) != 0 {
+		t.Errorf("expected depopulated posts, actual %v", list)
+	}
+}
@@ -0,0 +1,139 @@
+package gongo
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Field error kinds
+const (
+	RequiredErrorKind  = "required"
+	TypeErrorKind      = "type"
+	ValidatorErrorKind = "validator"
+	CastErrorKind      = "cast"
+	UndefinedErrorKind = "undefined"
+)
+
+// FieldError a validation failure at a single document path
+type FieldError struct {
+	Kind    string
+	Path    string
+	Value   interface{}
+	Message string
+	Err     error
+}
+
+// Error implements the error interface
+func (c *FieldError) Error() string {
+	return c.Message
+}
+
+// Unwrap returns the underlying error if any, this is typically
+// the error returned by a custom validator
+func (c *FieldError) Unwrap() error {
+	return c.Err
+}
+
+// ValidationError a collection of field errors keyed by dotted path
+type ValidationError struct {
+	Errors map[string]*FieldError
+}
+
+// creates a new empty validation error
+func newValidationError() *ValidationError {
+	return &ValidationError{
+		Errors: make(map[string]*FieldError),
+	}
+}
+
+// Error implements the error interface
+func (c *ValidationError) Error() string {
+	messages := make([]string, 0)
+	for _, path := range c.Paths() {
+		messages = append(messages, c.Errors[path].Message)
+	}
+	return fmt.Sprintf("validation failed: %s", strings.Join(messages, "; "))
+}
+
+// Paths returns the sorted list of paths that failed validation
+func (c *ValidationError) Paths() []string {
+	paths := make([]string, 0)
+	for path := range c.Errors {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// adds a field error, only the first error for a path is kept
+func (c *ValidationError) add(kind string, path []string, value interface{}, err error, format string, args ...interface{}) {
+	pathStr := strings.Join(path, ".")
+	if _, ok := c.Errors[pathStr]; ok {
+		return
+	}
+	c.Errors[pathStr] = &FieldError{
+		Kind:    kind,
+		Path:    pathStr,
+		Value:   value,
+		Message: fmt.Sprintf(format, args...),
+		Err:     err,
+	}
+}
+
+// returns true if any field errors have been collected
+func (c *ValidationError) hasErrors() bool {
+	return len(c.Errors) > 0
+}
+
+// VersionConflictError returned when saving a document that has been
+// modified by another writer since it was loaded
+type VersionConflictError struct {
+	ID      interface{}
+	Version interface{}
+}
+
+// Error implements the error interface
+func (c *VersionConflictError) Error() string {
+	return fmt.Sprintf("no matching document found for id %v and version %v", c.ID, c.Version)
+}
+
+// MiddlewareError the aggregated errors of an operation's middleware
+// including the errors returned by async middleware
+type MiddlewareError struct {
+	Operation string
+	Errors    []error
+}
+
+// Error implements the error interface
+func (c *MiddlewareError) Error() string {
+	messages := make([]string, 0)
+	for _, err := range c.Errors {
+		messages = append(messages, err.Error())
+	}
+	return fmt.Sprintf("%s middleware failed: %s", c.Operation, strings.Join(messages, "; "))
+}
+
+// Unwrap returns the collected errors
+func (c *MiddlewareError) Unwrap() []error {
+	return c.Errors
+}
+
+// CastError returned when a query value cannot be cast to the
+// type of the schema field at its path
+type CastError struct {
+	Path  string
+	Value interface{}
+	Type  interface{}
+	Err   error
+}
+
+// Error implements the error interface
+func (c *CastError) Error() string {
+	return fmt.Sprintf("failed to cast value %v to %s at path %q: %s", c.Value, c.Type, c.Path, c.Err.Error())
+}
+
+// Unwrap returns the underlying cast error
+func (c *CastError) Unwrap() error {
+	return c.Err
+}
@@ -0,0 +1,221 @@
+package gongo
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bhoriuchi/gongo/helpers"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// performs a deep build of the query, walking each path against the
+// schema and casting the values and operator arguments to the field types
+func (c *Model) deepQueryBuild(schema *Schema, filter bson.M, path []string) (bson.M, error) {
+	result := bson.M{}
+
+	for key, value := range filter {
+		switch key {
+
+		// logical operators contain a list of filters
+		case "$and", "$or", "$nor":
+			conditions, ok := value.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%s requires an array of filters", key)
+			}
+			built := make([]interface{}, 0)
+			for _, condition := range conditions {
+				m, ok := condition.(bson.M)
+				if !ok {
+					return nil, fmt.Errorf("%s requires an array of filters", key)
+				}
+				b, err := c.deepQueryBuild(schema, m, path)
+				if err != nil {
+					return nil, err
+				}
+				built = append(built, b)
+			}
+			result[key] = built
+			continue
+		}
+
+		// other top level operators are passed as is
+		if strings.HasPrefix(key, "$") {
+			result[key] = value
+			continue
+		}
+
+		// if the value is virtual, use the setter function. virtuals on
+		// nested schemas set their values relative to the nested schema
+		// and are then prefixed with its path
+		fieldPath := strings.Split(key, ".")
+		if config, nested, prefix := schema.virtualAtPath(fieldPath); config != nil {
+			if config.Set == nil {
+				return nil, fmt.Errorf("virtual %q cannot be queried", key)
+			}
+			set := bson.M{}
+			if err := config.Set(value, set); err != nil {
+				return nil, err
+			}
+			for k, v := range set {
+				setPath := append(append([]string{}, prefix...), k)
+				if field, element := nested.fieldAtPath(strings.Split(k, ".")); field != nil {
+					cast, err := c.castCondition(field, element, append(append([]string{}, path...), setPath...), v)
+					if err != nil {
+						return nil, err
+					}
+					v = cast
+				}
+				result[strings.Join(setPath, ".")] = v
+			}
+			continue
+		}
+
+		// paths not defined in the schema are passed as is
+		field, element := schema.fieldAtPath(fieldPath)
+		if field == nil {
+			result[key] = value
+			continue
+		}
+
+		fullPath := append(append([]string{}, path...), fieldPath...)
+		cast, err := c.castCondition(field, element, fullPath, value)
+		if err != nil {
+			return nil, err
+		}
+		result[key] = cast
+	}
+	return result, nil
+}
+
+// casts either an equality value or an operator document
+func (c *Model) castCondition(field *SchemaField, element bool, path []string, value interface{}) (interface{}, error) {
+	operators, ok := value.(bson.M)
+	if !ok || !isOperatorDocument(operators) {
+		return castQueryValue(field, element, path, value)
+	}
+
+	result := bson.M{}
+	for op, arg := range operators {
+		switch op {
+		case "$eq", "$ne":
+			cast, err := castQueryValue(field, element, path, arg)
+			if err != nil {
+				return nil, err
+			}
+			result[op] = cast
+
+		case "$gt", "$gte", "$lt", "$lte":
+			cast, err := castQueryValue(field, element, path, arg)
+			if err != nil {
+				// fractional bounds are valid ranges on integer fields
+				f, ferr := castRangeFloat(field, arg)
+				if ferr != nil {
+					return nil, err
+				}
+				cast = f
+			}
+			result[op] = cast
+
+		case "$in", "$nin", "$all":
+			if !helpers.IsArrayLike(arg) {
+				return nil, fmt.Errorf("%s at path %q requires an array", op, strings.Join(path, "."))
+			}
+			items := make([]interface{}, 0)
+			el := helpers.GetElement(arg)
+			for i := 0; i < el.Len(); i++ {
+				cast, err := castQueryValue(field, true, path, el.Index(i).Interface())
+				if err != nil {
+					return nil, err
+				}
+				items = append(items, cast)
+			}
+			result[op] = items
+
+		case "$not":
+			cast, err := c.castCondition(field, element, path, arg)
+			if err != nil {
+				return nil, err
+			}
+			result[op] = cast
+
+		case "$elemMatch":
+			conditions, ok := arg.(bson.M)
+			if !ok {
+				return nil, fmt.Errorf("$elemMatch at path %q requires a document", strings.Join(path, "."))
+			}
+			var cast interface{}
+			var err error
+			if field.schema != nil && !isOperatorDocument(conditions) {
+				cast, err = c.deepQueryBuild(field.schema, conditions, path)
+			} else {
+				cast, err = c.castCondition(field, true, path, conditions)
+			}
+			if err != nil {
+				return nil, err
+			}
+			result[op] = cast
+
+		default:
+			result[op] = arg
+		}
+	}
+	return result, nil
+}
+
+// casts a query value to the field type. values for array fields
+// can either be a single element or the entire array
+func castQueryValue(field *SchemaField, element bool, path []string, value interface{}) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+	if _, ok := value.(primitive.Regex); ok {
+		return value, nil
+	}
+
+	if field.isArray && !element && helpers.IsArrayLike(value) && !helpers.IsObjectID(value) {
+		items := make([]interface{}, 0)
+		el := helpers.GetElement(value)
+		for i := 0; i < el.Len(); i++ {
+			cast, err := castQueryValue(field, true, path, el.Index(i).Interface())
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, cast)
+		}
+		return items, nil
+	}
+
+	cast, err := castValue(field.elementType, value)
+	if err != nil {
+		return nil, &CastError{
+			Path:  strings.Join(path, "."),
+			Value: value,
+			Type:  field.elementType,
+			Err:   err,
+		}
+	}
+	return cast, nil
+}
+
+// casts a range bound on an integer field to a float
+func castRangeFloat(field *SchemaField, value interface{}) (interface{}, error) {
+	switch field.elementType {
+	case IntType, LongType:
+		return castValue(FloatType, value)
+	}
+	return nil, fmt.Errorf("cannot cast range bound to %s", FloatType)
+}
+
+// returns true if the document keys are all operators
+func isOperatorDocument(doc bson.M) bool {
+	if len(doc) == 0 {
+		return false
+	}
+	for key := range doc {
+		if !strings.HasPrefix(key, "$") {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,169 @@
+package gongo
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestFilterCasting(t *testing.T) {
+	g := New()
+	barSchema := Schema{
+		Fields: SchemaFieldMap{
+			"size": {
+				Type: IntType,
+			},
+		},
+	}
+	fooSchema := Schema{
+		Fields: SchemaFieldMap{
+			"owner": {
+				Type: ObjectIDType,
+			},
+			"age": {
+				Type: IntType,
+			},
+			"tags": {
+				Type: []interface{}{StringType},
+			},
+			"bars": {
+				Type: []interface{}{barSchema},
+			},
+		},
+	}
+
+	foo, err := g.Model("Foo", &fooSchema)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	owner := primitive.NewObjectID()
+	other := primitive.NewObjectID()
+	actual, err := foo.buildQuery(context.Background(), bson.M{
+		"owner": owner.Hex(),
+		"age":   bson.M{"$gt": "42", "$ne": 50.0},
+		"tags":  bson.M{"$in": []string{"a", "b"}},
+		"bars":  bson.M{"$elemMatch": bson.M{"size": bson.M{"$gte": "2"}}},
+		"$or": []interface{}{
+			bson.M{"owner": bson.M{"$in": []interface{}{other.Hex()}}},
+			bson.M{"bars.size": "3"},
+		},
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	expected := &bson.M{
+		"owner": owner,
+		"age":   bson.M{"$gt": 42, "$ne": 50},
+		"tags":  bson.M{"$in": []interface{}{"a", "b"}},
+		"bars":  bson.M{"$elemMatch": bson.M{"size": bson.M{"$gte": 2}}},
+		"$or": []interface{}{
+			bson.M{"owner": bson.M{"$in": []interface{}{other}}},
+			bson.M{"bars.size": 3},
+		},
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected %v, actual %v", expected, actual)
+		return
+	}
+
+	_, err = foo.buildQuery(context.Background(), bson.M{"age": bson.M{"$in": []interface{}{"1", "x"}}})
+	var castErr *CastError
+	if !errors.As(err, &castErr) {
+		t.Errorf("expected CastError, actual %v", err)
+		return
+	}
+	if castErr.Path != "age" || castErr.Value != "x" {
+		t.Errorf("unexpected cast error %v", castErr)
+		return
+	}
+
+	// fractional range bounds on integer fields are kept as floats
+	actual, err = foo.buildQuery(context.Background(), bson.M{"age": bson.M{"$gt": 42.5, "$lte": "60.5"}})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	expected = &bson.M{"age": bson.M{"$gt": 42.5, "$lte": 60.5}}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected %v, actual %v", expected, actual)
+		return
+	}
+	if _, err := foo.buildQuery(context.Background(), bson.M{"age": 42.5}); !errors.As(err, &castErr) {
+		t.Errorf("expected CastError for fractional equality, actual %v", err)
+		return
+	}
+}
+
+func TestFilterSanitize(t *testing.T) {
+	g := New(&Options{SanitizeFilter: true})
+	fooSchema := Schema{
+		Fields: SchemaFieldMap{
+			"password": {
+				Type: MixedType,
+			},
+			"age": {
+				Type: IntType,
+			},
+		},
+	}
+
+	foo, err := g.Model("Foo", &fooSchema)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	ctx := context.Background()
+
+	// operators in values are escaped
+	actual, err := foo.buildQuery(ctx, bson.M{"password": bson.M{"$ne": nil}})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	expected := &bson.M{"password": bson.M{"$eq": bson.M{"$ne": nil}}}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected %v, actual %v", expected, actual)
+		return
+	}
+
+	// top level operators are rejected
+	var unsafeErr *UnsafeFilterError
+	_, err = foo.buildQuery(ctx, bson.M{"$where": "sleep(1000)"})
+	if !errors.As(err, &unsafeErr) {
+		t.Errorf("expected UnsafeFilterError, actual %v", err)
+		return
+	}
+
+	// trusted values are not escaped
+	actual, err = foo.buildQuery(ctx, bson.M{"age": Trusted(bson.M{"$gt": "18"})})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	expected = &bson.M{"age": bson.M{"$gt": 18}}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected %v, actual %v", expected, actual)
+		return
+	}
+
+	// per call override
+	if _, err := foo.buildQuery(ctx, WithFilterOptions(bson.M{"$where": "true"}, NewFilterOptions().SetSanitizeFilter(false))); err != nil {
+		t.Errorf("expected sanitize override to allow operator, got %v", err)
+		return
+	}
+
+	// strict queries reject undefined paths
+	_, err = foo.buildQuery(ctx, WithFilterOptions(bson.M{"missing": 1}, NewFilterOptions().SetStrictQuery(true)))
+	if !errors.As(err, &unsafeErr) {
+		t.Errorf("expected UnsafeFilterError, actual %v", err)
+		return
+	}
+}
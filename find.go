@@ -1,6 +1,7 @@
 package gongo
 
 import (
+	"context"
 	"fmt"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -14,47 +15,31 @@ func (c *Model) Find(filter interface{}, opts ...*options.FindOptions) (Document
 
 // FindWithTimeout finds documents
 func (c *Model) FindWithTimeout(filter interface{}, timeout *int, opts ...*options.FindOptions) (DocumentList, error) {
-	results := make(DocumentList, 0)
-	m := bson.M{}
-	if filter != nil {
-		if err := c.gongo.weakDecode(filter, &m); err != nil {
-			return nil, err
-		}
-	}
-
-	query, err := c.applyVirtualQueryDocument(&m)
-	if err != nil {
-		return nil, err
-	}
-
-	// create a context
 	ctx, cancelFunc := newContext(timeout)
 	defer cancelFunc()
+	return c.FindCtx(ctx, filter, opts...)
+}
 
-	// perform the find operation
-	cur, err := c.Collection().Find(ctx, query, opts...)
+// FindCtx finds documents using the provided context
+func (c *Model) FindCtx(ctx context.Context, filter interface{}, opts ...*options.FindOptions) (DocumentList, error) {
+	results := make(DocumentList, 0)
+	cur, err := c.FindIterCtx(ctx, filter, opts...)
 	if err != nil {
 		return nil, err
 	}
+	defer cur.Close()
 
-	defer cur.Close(ctx)
-	if err := cur.Err(); err != nil {
-		return nil, err
-	}
-
-	// decode all the results
-	var temp []bson.M
-	if err := cur.All(ctx, &temp); err != nil {
-		return nil, err
-	}
-
-	for _, result := range temp {
-		doc, err := c.New(result)
+	// hydrate each result as it is read
+	for cur.Next(ctx) {
+		doc, err := cur.Document()
 		if err != nil {
 			return nil, err
 		}
 		results = append(results, doc)
 	}
+	if err := cur.Err(); err != nil {
+		return nil, err
+	}
 
 	return results, nil
 }
@@ -66,26 +51,37 @@ func (c *Model) FindOne(filter interface{}, opts ...*options.FindOneOptions) (*D
 
 // FindOneWithTimeout finds one document
 func (c *Model) FindOneWithTimeout(filter interface{}, timeout *int, opts ...*options.FindOneOptions) (*Document, error) {
-	m := bson.M{}
-	if filter != nil {
-		if err := c.gongo.weakDecode(filter, &m); err != nil {
-			return nil, err
-		}
-	}
+	ctx, cancelFunc := newContext(timeout)
+	defer cancelFunc()
+	return c.FindOneCtx(ctx, filter, opts...)
+}
 
-	query, err := c.applyVirtualQueryDocument(&m)
+// FindOneCtx finds one document using the provided context
+func (c *Model) FindOneCtx(ctx context.Context, filter interface{}, opts ...*options.FindOneOptions) (*Document, error) {
+	query, err := c.buildQuery(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
 
-	// create a context
-	ctx, cancelFunc := newContext(timeout)
-	defer cancelFunc()
+	// apply pre-middleware
+	findOpts := options.MergeFindOneOptions(opts...)
+	q := &QueryContext{
+		Operation:  "findOne",
+		Model:      c,
+		Filter:     *query,
+		Projection: findOpts.Projection,
+		Options:    findOpts,
+	}
+	ctx, err = c.applyPreQueryMiddleware(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	findOpts.Projection = q.Projection
 
 	// perform the find operation
-	result := c.Collection().FindOne(ctx, query, opts...)
+	result := c.Collection().FindOne(ctx, q.Filter, findOpts)
 	if err := result.Err(); err != nil {
-		return nil, err
+		return nil, c.schema.applyErrorMiddleware(ctx, "findOne", q.Filter, err)
 	}
 
 	var temp bson.M
@@ -93,7 +89,12 @@ func (c *Model) FindOneWithTimeout(filter interface{}, timeout *int, opts ...*op
 		return nil, err
 	}
 
-	return c.New(temp)
+	// apply post middleware with the loaded document
+	if err := c.schema.applyPostMiddleware(ctx, "findOne", temp); err != nil {
+		return nil, err
+	}
+
+	return c.hydrate(ctx, temp)
 }
 
 // FindByID finds one document by id
@@ -103,8 +104,15 @@ func (c *Model) FindByID(id interface{}, opts ...*options.FindOneOptions) (*Docu
 
 // FindByIDWithTimeout finds one document by id
 func (c *Model) FindByIDWithTimeout(id interface{}, timeout *int, opts ...*options.FindOneOptions) (*Document, error) {
+	ctx, cancelFunc := newContext(timeout)
+	defer cancelFunc()
+	return c.FindByIDCtx(ctx, id, opts...)
+}
+
+// FindByIDCtx finds one document by id using the provided context
+func (c *Model) FindByIDCtx(ctx context.Context, id interface{}, opts ...*options.FindOneOptions) (*Document, error) {
 	if id == nil {
 		return nil, fmt.Errorf("required id not provided")
 	}
-	return c.FindOneWithTimeout(bson.M{"_id": id}, timeout, opts...)
+	return c.FindOneCtx(ctx, bson.M{"_id": id}, opts...)
 }
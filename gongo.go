@@ -3,6 +3,7 @@ package gongo
 import (
 	"context"
 	"fmt"
+	"reflect"
 	"time"
 
 	"github.com/bhoriuchi/gongo/helpers"
@@ -13,10 +14,20 @@ import (
 	"go.mongodb.org/mongo-driver/x/mongo/driver/connstring"
 )
 
+// DefaultAsyncMiddlewareWait the default maximum time to wait for async middleware
+const DefaultAsyncMiddlewareWait = 30 * time.Second
+
 // Options gongo options
 type Options struct {
 	FieldTag        string
 	DefaultDatabase string
+	SanitizeFilter  bool
+	StrictQuery     bool
+
+	// AsyncMiddlewareWait is the maximum time to wait for async middleware
+	// to complete, zero uses DefaultAsyncMiddlewareWait and a negative
+	// wait waits until the middleware completes or the context is done
+	AsyncMiddlewareWait time.Duration
 }
 
 // Gongo main interface
@@ -29,6 +40,7 @@ type Gongo struct {
 	client        *mongo.Client
 	hub           chan string
 	fieldTag      string
+	plugins       []*pluginConfig
 }
 
 // M gets a model from the registered models
@@ -46,8 +58,9 @@ func New(opts ...*Options) *Gongo {
 		models:    make(map[string]*Model),
 		hub:       make(chan string),
 		options: &Options{
-			FieldTag:        "json",
-			DefaultDatabase: "test",
+			FieldTag:            "json",
+			DefaultDatabase:     "test",
+			AsyncMiddlewareWait: DefaultAsyncMiddlewareWait,
 		},
 	}
 
@@ -61,6 +74,11 @@ func New(opts ...*Options) *Gongo {
 			if o.DefaultDatabase != "" {
 				g.options.DefaultDatabase = o.DefaultDatabase
 			}
+			g.options.SanitizeFilter = o.SanitizeFilter
+			g.options.StrictQuery = o.StrictQuery
+			if o.AsyncMiddlewareWait != 0 {
+				g.options.AsyncMiddlewareWait = o.AsyncMiddlewareWait
+			}
 		}
 	}
 
@@ -119,9 +137,6 @@ func (c *Gongo) Model(name string, schema *Schema, opts ...*ModelOptions) (*Mode
 		return nil, fmt.Errorf("no model name specified")
 	}
 
-	// add reference to root gongo
-	schema.gongo = c
-
 	// initialize the schema
 	if err := schema.init(); err != nil {
 		return nil, err
@@ -154,6 +169,14 @@ func (c *Gongo) Model(name string, schema *Schema, opts ...*ModelOptions) (*Mode
 		return nil, fmt.Errorf("type %q has already been registered", name)
 	}
 
+	// apply the global plugins
+	if err := c.applyPlugins(schema); err != nil {
+		return nil, err
+	}
+
+	// add reference to root gongo
+	schema.setGongo(c)
+
 	// create a copy of the schema
 	newSchema := schema.copy()
 
@@ -166,6 +189,35 @@ func (c *Gongo) Model(name string, schema *Schema, opts ...*ModelOptions) (*Mode
 		})
 	}
 
+	// add the version key field
+	if versionKey := newSchema.Options.versionKey(); versionKey != "" {
+		if _, ok := newSchema.Fields[versionKey]; !ok {
+			field := &SchemaField{Type: IntType}
+			if err := field.init(versionKey); err != nil {
+				return nil, err
+			}
+			newSchema.Fields[versionKey] = field
+		}
+	}
+
+	// add the timestamp fields
+	if timestamps := newSchema.Options.Timestamps; timestamps != nil {
+		for _, key := range []string{timestamps.createdAtKey(), timestamps.updatedAtKey()} {
+			if _, ok := newSchema.Fields[key]; !ok {
+				field := &SchemaField{Type: DateType}
+				if err := field.init(key); err != nil {
+					return nil, err
+				}
+				newSchema.Fields[key] = field
+			}
+		}
+	}
+
+	// methods cannot share a name with a field or virtual
+	if err := newSchema.checkMethodCollisions(); err != nil {
+		return nil, err
+	}
+
 	// create the model
 	// field tags are mapped at model registration because
 	// the schema allows for the tag definition to be overriden
@@ -188,8 +240,12 @@ func (c *Gongo) Model(name string, schema *Schema, opts ...*ModelOptions) (*Mode
 	return model, nil
 }
 
-// performs a weakDecode
+// performs a weakDecode. struct inputs are first converted to documents
+// so that bson types like time.Time are kept instead of becoming maps
 func (c *Gongo) weakDecode(input, output interface{}) error {
+	if helpers.GetKind(input) == reflect.Struct && !isOpaqueType(reflect.TypeOf(input)) {
+		input = structToDocument(reflect.ValueOf(input), c.options.FieldTag)
+	}
 	config := &mapstructure.DecoderConfig{
 		Metadata:         nil,
 		Result:           output,
@@ -203,7 +259,7 @@ func (c *Gongo) weakDecode(input, output interface{}) error {
 	return decoder.Decode(input)
 }
 
-// creates a new context
+// creates a new context from an optional timeout in seconds
 func newContext(timeout ...*int) (context.Context, context.CancelFunc) {
 	if len(timeout) > 0 {
 		to := timeout[0]
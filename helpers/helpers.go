@@ -77,7 +77,8 @@ func ToInterface(src interface{}, dest interface{}) error {
 
 // IsObjectID tests if obj is an object id
 func IsObjectID(obj interface{}) bool {
-	return GetElement(obj).Type() == reflect.TypeOf(primitive.ObjectID([12]byte{}))
+	el := GetElement(obj)
+	return el.IsValid() && el.Type() == reflect.TypeOf(primitive.ObjectID([12]byte{}))
 }
 
 // DotPathToSlashPath converts a dot path to a dir path
@@ -2,26 +2,21 @@ package helpers
 
 import (
 	"fmt"
+	"reflect"
+	"strings"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-// VirtualSetObjectID sets an object id
+// VirtualSetObjectID sets an object id. when used in a query the value
+// can also be a list of ids or an operator document containing ids
 func VirtualSetObjectID(fieldName string) func(value interface{}, doc bson.M) error {
 	return func(value interface{}, doc bson.M) error {
 		if fieldName == "" {
 			return fmt.Errorf("VirtualSetObjectID has no field name specified")
 		}
-		if IsObjectID(value) {
-			doc[fieldName] = value
-			return nil
-		}
-		id := fmt.Sprintf("%v", value)
-		if id == "" {
-			return fmt.Errorf("no object id specified")
-		}
-		objectID, err := primitive.ObjectIDFromHex(id)
+		objectID, err := toObjectID(value)
 		if err != nil {
 			return err
 		}
@@ -31,6 +26,55 @@ func VirtualSetObjectID(fieldName string) func(value interface{}, doc bson.M) er
 	}
 }
 
+// converts a value, list of values, or operator document to object ids
+func toObjectID(value interface{}) (interface{}, error) {
+	if value == nil || IsObjectID(value) {
+		return value, nil
+	}
+
+	switch kind := GetKind(value); kind {
+	case reflect.Map:
+		result := bson.M{}
+		el := GetElement(value)
+		for _, key := range el.MapKeys() {
+			op := fmt.Sprintf("%v", key.Interface())
+			v := el.MapIndex(key).Interface()
+			switch op {
+			case "$exists", "$type":
+				result[op] = v
+				continue
+			}
+			if !strings.HasPrefix(op, "$") {
+				return nil, fmt.Errorf("invalid object id operator %q", op)
+			}
+			id, err := toObjectID(v)
+			if err != nil {
+				return nil, err
+			}
+			result[op] = id
+		}
+		return result, nil
+
+	case reflect.Slice, reflect.Array:
+		result := make([]interface{}, 0)
+		el := GetElement(value)
+		for i := 0; i < el.Len(); i++ {
+			id, err := toObjectID(el.Index(i).Interface())
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, id)
+		}
+		return result, nil
+	}
+
+	id := fmt.Sprintf("%v", value)
+	if id == "" {
+		return nil, fmt.Errorf("no object id specified")
+	}
+	return primitive.ObjectIDFromHex(id)
+}
+
 // VirtualGetObjectIDAsHexString returns the specified field
 // containing an ObjectID as a hex string
 func VirtualGetObjectIDAsHexString(fieldName string) func(doc bson.M) (interface{}, error) {
@@ -0,0 +1,105 @@
+package gongo
+
+import (
+	"fmt"
+)
+
+// MethodFunc an instance method called on a document
+type MethodFunc func(doc *Document, args ...interface{}) (interface{}, error)
+
+// StaticFunc a static method called on a model
+type StaticFunc func(m *Model, args ...interface{}) (interface{}, error)
+
+// returns an error if the name is already used by a field,
+// virtual, method or static on the schema
+func (c *Schema) checkMethodName(name string) error {
+	if name == "" {
+		return fmt.Errorf("method name cannot be empty")
+	}
+	if _, ok := c.Fields[name]; ok {
+		return fmt.Errorf("method name %q collides with a field", name)
+	}
+	if c.keyIsVirtual(name) {
+		return fmt.Errorf("method name %q collides with a virtual", name)
+	}
+	if _, ok := c.methods[name]; ok {
+		return fmt.Errorf("method %q is already registered", name)
+	}
+	if _, ok := c.statics[name]; ok {
+		return fmt.Errorf("static %q is already registered", name)
+	}
+	return nil
+}
+
+// returns an error if a method or static shares its name with a field or
+// virtual. virtuals and the fields added by the model are checked here
+// because they can be added after the methods are registered
+func (c *Schema) checkMethodCollisions() error {
+	check := func(kind, name string) error {
+		if _, ok := c.Fields[name]; ok {
+			return fmt.Errorf("%s name %q collides with a field", kind, name)
+		} else if c.keyIsVirtual(name) {
+			return fmt.Errorf("%s name %q collides with a virtual", kind, name)
+		}
+		return nil
+	}
+	for name := range c.methods {
+		if err := check("method", name); err != nil {
+			return err
+		}
+	}
+	for name := range c.statics {
+		if err := check("static", name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Method registers an instance method that can be invoked with Document.Call
+func (c *Schema) Method(name string, fn MethodFunc) error {
+	if fn == nil {
+		return fmt.Errorf("method %q cannot be nil", name)
+	}
+	if err := c.checkMethodName(name); err != nil {
+		return err
+	}
+	if c.methods == nil {
+		c.methods = make(map[string]MethodFunc)
+	}
+	c.methods[name] = fn
+	return nil
+}
+
+// Static registers a static method that can be invoked with Model.Call
+func (c *Schema) Static(name string, fn StaticFunc) error {
+	if fn == nil {
+		return fmt.Errorf("static %q cannot be nil", name)
+	}
+	if err := c.checkMethodName(name); err != nil {
+		return err
+	}
+	if c.statics == nil {
+		c.statics = make(map[string]StaticFunc)
+	}
+	c.statics[name] = fn
+	return nil
+}
+
+// Call invokes the named instance method on the document
+func (c *Document) Call(name string, args ...interface{}) (interface{}, error) {
+	fn, ok := c.model.schema.methods[name]
+	if !ok {
+		return nil, fmt.Errorf("method %q is not defined on model %q", name, c.model.collectionName)
+	}
+	return fn(c, args...)
+}
+
+// Call invokes the named static method on the model
+func (c *Model) Call(name string, args ...interface{}) (interface{}, error) {
+	fn, ok := c.schema.statics[name]
+	if !ok {
+		return nil, fmt.Errorf("static %q is not defined on model %q", name, c.collectionName)
+	}
+	return fn(c, args...)
+}
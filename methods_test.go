@@ -0,0 +1,120 @@
+package gongo
+
+import (
+	"fmt"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestSchemaMethods(t *testing.T) {
+	g := New()
+	schema := Schema{
+		Fields: SchemaFieldMap{
+			"name": {
+				Type: StringType,
+			},
+		},
+	}
+	schema.Virtual(&VirtualConfig{
+		Name: "displayName",
+		Get: func(doc bson.M) (interface{}, error) {
+			return doc["name"], nil
+		},
+	})
+
+	if err := schema.Method("greet", func(doc *Document, args ...interface{}) (interface{}, error) {
+		name, err := doc.Get("name")
+		if err != nil {
+			return nil, err
+		}
+		return fmt.Sprintf("hello %v", name), nil
+	}); err != nil {
+		t.Error(err)
+		return
+	}
+	if err := schema.Static("findByName", func(m *Model, args ...interface{}) (interface{}, error) {
+		return args[0], nil
+	}); err != nil {
+		t.Error(err)
+		return
+	}
+
+	// collisions
+	noop := func(doc *Document, args ...interface{}) (interface{}, error) { return nil, nil }
+	for _, name := range []string{"name", "displayName", "greet", "findByName"} {
+		if err := schema.Method(name, noop); err == nil {
+			t.Errorf("expected collision error for %q", name)
+			return
+		}
+	}
+
+	users, err := g.Model("User", &schema)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	user, err := users.New(bson.M{"name": "foo"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if actual, err := user.Call("greet"); err != nil {
+		t.Error(err)
+		return
+	} else if actual != "hello foo" {
+		t.Errorf("expected %q, actual %v", "hello foo", actual)
+		return
+	}
+	if actual, err := users.Call("findByName", "foo"); err != nil {
+		t.Error(err)
+		return
+	} else if actual != "foo" {
+		t.Errorf("expected %q, actual %v", "foo", actual)
+		return
+	}
+	if _, err := user.Call("missing"); err == nil {
+		t.Error("expected error calling undefined method")
+	}
+}
+
+func TestSchemaMethodCollisions(t *testing.T) {
+	g := New()
+	noop := func(doc *Document, args ...interface{}) (interface{}, error) { return nil, nil }
+
+	// virtuals added after a method
+	schema := Schema{Fields: SchemaFieldMap{"name": {Type: StringType}}}
+	if err := schema.Method("fullName", noop); err != nil {
+		t.Error(err)
+		return
+	}
+	schema.Virtual(&VirtualConfig{Name: "fullName"})
+	if _, err := g.Model("Virtual", &schema); err == nil {
+		t.Errorf("expected virtual collision error")
+		return
+	}
+
+	// fields added by the model
+	for name, options := range map[string]*SchemaOptions{
+		"id":              {},
+		DefaultVersionKey: {},
+		"createdAt":       {Timestamps: &TimestampsOptions{}},
+		"updatedAt":       {Timestamps: &TimestampsOptions{}},
+	} {
+		schema := Schema{
+			Fields:  SchemaFieldMap{"name": {Type: StringType}},
+			Options: options,
+		}
+		if err := schema.Static(name, func(m *Model, args ...interface{}) (interface{}, error) {
+			return nil, nil
+		}); err != nil {
+			t.Error(err)
+			return
+		}
+		if _, err := g.Model(name, &schema); err == nil {
+			t.Errorf("expected collision error for %q", name)
+			return
+		}
+	}
+}
@@ -1,6 +1,39 @@
 package gongo
 
-import "go.mongodb.org/mongo-driver/bson"
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// operations that middleware can be registered for
+var middlewareOperations = map[string]bool{
+	"aggregate":              true,
+	"save":                   true,
+	"validate":               true,
+	"remove":                 true,
+	"init":                   true,
+	"count":                  true,
+	"estimatedDocumentCount": true,
+	"distinct":               true,
+	"deleteMany":             true,
+	"deleteOne":              true,
+	"find":                   true,
+	"findOne":                true,
+	"findOneAndDelete":       true,
+	"findOneAndRemove":       true,
+	"findOneAndUpdate":       true,
+	"update":                 true,
+	"updateOne":              true,
+	"updateMany":             true,
+	"replaceOne":             true,
+}
+
+// NextFunc calls the next middleware in the chain and returns its error
+type NextFunc func() error
 
 // PreMiddleware a middleware
 type PreMiddleware struct {
@@ -16,122 +49,327 @@ type PostMiddleware struct {
 	Async     bool
 }
 
-// PreMiddlewareFunc middleware for document
-type PreMiddlewareFunc func(documentOrQuery bson.M) error
+// ErrorMiddleware a middleware
+type ErrorMiddleware struct {
+	Operation string
+	Handler   ErrorMiddlewareFunc
+}
 
-// PostMiddlewareFunc middleware for post
-type PostMiddlewareFunc func(document bson.M, err error) error
+// PreMiddlewareFunc middleware for document, the context is the
+// one passed to the operation. returning an error aborts the operation.
+// returning nil without calling next skips the remaining middleware
+// but the operation is still performed
+type PreMiddlewareFunc func(ctx context.Context, documentOrQuery bson.M, next NextFunc) error
 
-// middleware is kept as a map with integer keys to ensure
-// middlewares are called in the order they were registered
+// PostMiddlewareFunc middleware run after a successful operation, the
+// context is the one passed to the operation. document operations, findOne,
+// findOneAndUpdate and findOneAndDelete receive the resulting document.
+// update operations receive the update and delete operations the filter.
+// find, aggregate, count, estimatedDocumentCount and distinct receive the
+// query filter because their results are read after the middleware runs,
+// the rest of the query is available with QueryFromContext
+type PostMiddlewareFunc func(ctx context.Context, document bson.M, next NextFunc) error
+
+// ErrorMiddlewareFunc middleware run when an operation fails. the returned
+// error replaces the operation error, returning nil keeps the current error
+type ErrorMiddlewareFunc func(ctx context.Context, document bson.M, err error) error
+
+// middleware is kept in slices in the order they were registered
 type middlewareConfig struct {
-	pre  map[int]*PreMiddleware
-	post map[int]*PostMiddleware
+	pre  []*PreMiddleware
+	post []*PostMiddleware
+	err  []*ErrorMiddleware
 }
 
 func (c *middlewareConfig) copy() middlewareConfig {
-	pre := make(map[int]*PreMiddleware)
-	post := make(map[int]*PostMiddleware)
-	for k, v := range c.pre {
-		pre[k] = v
+	return middlewareConfig{
+		pre:  append([]*PreMiddleware{}, c.pre...),
+		post: append([]*PostMiddleware{}, c.post...),
+		err:  append([]*ErrorMiddleware{}, c.err...),
 	}
-	for k, v := range c.post {
-		post[k] = v
+}
+
+// returns an error if middleware cannot be registered for the operation
+func checkMiddlewareOperation(operation string) error {
+	if !middlewareOperations[operation] {
+		return fmt.Errorf("unsupported middleware operation %q", operation)
 	}
-	return middlewareConfig{
-		pre:  pre,
-		post: post,
+	return nil
+}
+
+// Pre adds pre middleware. an error is returned for operations that
+// do not support middleware, so unlike earlier versions that returned
+// the schema, calls to Pre cannot be chained
+func (c *Schema) Pre(operation string, handler PreMiddlewareFunc, async ...*bool) error {
+	if err := checkMiddlewareOperation(operation); err != nil {
+		return err
+	} else if handler == nil {
+		return fmt.Errorf("middleware handler cannot be nil")
+	}
+	isAsync := false
+	if len(async) > 0 && async[0] != nil {
+		isAsync = *async[0]
+	}
+	if c.middleware == nil {
+		c.middleware = &middlewareConfig{}
 	}
+	c.middleware.pre = append(c.middleware.pre, &PreMiddleware{
+		Operation: operation,
+		Handler:   handler,
+		Async:     isAsync,
+	})
+	return nil
 }
 
-// Pre adds pre middleware
-func (c *Schema) Pre(operation string, handler PreMiddlewareFunc, async ...*bool) *Schema {
+// Post adds post middleware. an error is returned for operations that
+// do not support middleware, so calls to Post cannot be chained
+func (c *Schema) Post(operation string, handler PostMiddlewareFunc, async ...*bool) error {
+	if err := checkMiddlewareOperation(operation); err != nil {
+		return err
+	} else if handler == nil {
+		return fmt.Errorf("middleware handler cannot be nil")
+	}
 	isAsync := false
-	if len(async) > 0 {
-		a := async[0]
-		isAsync = *a
-	}
-	switch operation {
-	case "save", "validate", "remove", "init", "count", "deleteMany",
-		"deleteOne", "find", "findOne", "findOneAndDelete", "findOneAndRemove",
-		"findOneAndUpdate", "update", "updateOne", "updateMany":
-
-		c.middleware.pre[len(c.middleware.pre)] = &PreMiddleware{
-			Operation: operation,
-			Handler:   handler,
-			Async:     isAsync,
+	if len(async) > 0 && async[0] != nil {
+		isAsync = *async[0]
+	}
+	if c.middleware == nil {
+		c.middleware = &middlewareConfig{}
+	}
+	c.middleware.post = append(c.middleware.post, &PostMiddleware{
+		Operation: operation,
+		Handler:   handler,
+		Async:     isAsync,
+	})
+	return nil
+}
+
+// OnError adds error middleware, it can be used to translate
+// driver errors such as duplicate keys into domain errors. validation
+// errors during a save are passed to the validate error middleware
+// first and its result is then passed to the save error middleware
+func (c *Schema) OnError(operation string, handler ErrorMiddlewareFunc) error {
+	if err := checkMiddlewareOperation(operation); err != nil {
+		return err
+	} else if handler == nil {
+		return fmt.Errorf("middleware handler cannot be nil")
+	}
+	if c.middleware == nil {
+		c.middleware = &middlewareConfig{}
+	}
+	c.middleware.err = append(c.middleware.err, &ErrorMiddleware{
+		Operation: operation,
+		Handler:   handler,
+	})
+	return nil
+}
+
+// a single link in a middleware chain
+type middlewareLink struct {
+	handler func(ctx context.Context, document bson.M, next NextFunc) error
+	async   bool
+}
+
+// collects the errors of async middleware
+type asyncMiddleware struct {
+	wg    sync.WaitGroup
+	mx    sync.Mutex
+	count int
+	errs  []error
+}
+
+// runs the handler in a goroutine on a copy of the document so that
+// it does not race with the operation mutating the original
+func (c *asyncMiddleware) run(ctx context.Context, handler func(ctx context.Context, document bson.M, next NextFunc) error, document bson.M) {
+	doc := cloneDocument(&document)
+	c.count++
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		defer func() {
+			if r := recover(); r != nil {
+				c.addError(fmt.Errorf("async middleware panic: %v", r))
+			}
+		}()
+		if err := handler(ctx, doc, func() error { return nil }); err != nil {
+			c.addError(err)
 		}
-		return c
+	}()
+}
+
+func (c *asyncMiddleware) addError(err error) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	c.errs = append(c.errs, err)
+}
+
+// waits for the async middleware to complete. a negative wait
+// waits until all handlers complete or the context is done
+func (c *asyncMiddleware) wait(ctx context.Context, wait time.Duration) []error {
+	if c.count == 0 {
+		return nil
+	}
+
+	done := make(chan struct{})
+	go func() {
+		c.wg.Wait()
+		close(done)
+	}()
+
+	var timeout <-chan time.Time
+	if wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case <-done:
+	case <-timeout:
+		c.addError(fmt.Errorf("timed out after %s waiting for async middleware", wait))
+	case <-ctx.Done():
+		c.addError(ctx.Err())
 	}
-	return c
+
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	return append([]error{}, c.errs...)
 }
 
-// Post adds post middleware
-func (c *Schema) Post(operation string, handler PostMiddlewareFunc, async ...*bool) *Schema {
-	isAsync := false
-	if len(async) > 0 {
-		a := async[0]
-		isAsync = *a
-	}
-
-	switch operation {
-	case "save", "validate", "remove", "init", "count", "deleteMany",
-		"deleteOne", "find", "findOne", "findOneAndDelete", "findOneAndRemove",
-		"findOneAndUpdate", "update", "updateOne", "updateMany":
-		c.middleware.post[len(c.middleware.post)] = &PostMiddleware{
-			Operation: operation,
-			Handler:   handler,
-			Async:     isAsync,
+// runs the chain starting at index i. each handler is passed a next
+// function that runs the rest of the chain at most once
+func runMiddlewareChain(ctx context.Context, document bson.M, chain []middlewareLink, i int, async *asyncMiddleware) error {
+	if i >= len(chain) {
+		return nil
+	}
+
+	called := false
+	var nextErr error
+	next := func() error {
+		if !called {
+			called = true
+			nextErr = runMiddlewareChain(ctx, document, chain, i+1, async)
 		}
-		return c
+		return nextErr
+	}
+
+	link := chain[i]
+	if link.async {
+		async.run(ctx, link.handler, document)
+		return next()
+	}
+	if err := link.handler(ctx, document, next); err != nil {
+		return err
+	}
+	return nextErr
+}
+
+// runs the chain and joins any async middleware, errors from
+// the chain and the async middleware are aggregated
+func (c *Schema) runMiddleware(ctx context.Context, operation string, document bson.M, chain []middlewareLink) error {
+	wait := DefaultAsyncMiddlewareWait
+	if c.gongo != nil {
+		wait = c.gongo.options.AsyncMiddlewareWait
+	}
+
+	async := &asyncMiddleware{}
+	err := runMiddlewareChain(ctx, document, chain, 0, async)
+	errs := async.wait(ctx, wait)
+	if len(errs) == 0 {
+		return err
+	} else if err != nil {
+		errs = append([]error{err}, errs...)
+	}
+	return &MiddlewareError{
+		Operation: operation,
+		Errors:    errs,
 	}
-	return c
 }
 
 // apply the pre middleware
-func (c *Schema) applyPreMiddleware(operation string, documentOrQuery bson.M) error {
+func (c *Schema) applyPreMiddleware(ctx context.Context, operation string, documentOrQuery bson.M) error {
 	if c.middleware == nil {
 		return nil
 	}
-
-	// loop using integer iterator to keep order
-	for i := 0; i < len(c.middleware.pre); i++ {
-		if mw, ok := c.middleware.pre[i]; ok {
-			if mw.Operation == operation {
-				if !mw.Async {
-					if err := mw.Handler(documentOrQuery); err != nil {
-						return err
-					}
-				} else {
-					// run async as goroutine
-					go mw.Handler(documentOrQuery)
-				}
-			}
+	chain := make([]middlewareLink, 0)
+	for _, mw := range c.middleware.pre {
+		if mw.Operation == operation {
+			chain = append(chain, middlewareLink{handler: mw.Handler, async: mw.Async})
 		}
 	}
-	return nil
+	return c.runMiddleware(ctx, operation, documentOrQuery, chain)
 }
 
 // apply the post middleware
-func (c *Schema) applyPostMiddleware(operation string, document bson.M, err error) error {
+func (c *Schema) applyPostMiddleware(ctx context.Context, operation string, document bson.M) error {
 	if c.middleware == nil {
 		return nil
 	}
+	chain := make([]middlewareLink, 0)
+	for _, mw := range c.middleware.post {
+		if mw.Operation == operation {
+			chain = append(chain, middlewareLink{handler: mw.Handler, async: mw.Async})
+		}
+	}
+	return c.runMiddleware(ctx, operation, document, chain)
+}
 
-	// loop using integer iterator to keep order
-	for i := 0; i < len(c.middleware.post); i++ {
-		if mw, ok := c.middleware.post[i]; ok {
-			if mw.Operation == operation {
-				if !mw.Async {
-					if err := mw.Handler(document, err); err != nil {
-						return err
-					}
-				} else {
-					// run async as goroutine
-					go mw.Handler(document, err)
-				}
+// apply the error middleware, each handler receives the
+// error returned by the previous one
+func (c *Schema) applyErrorMiddleware(ctx context.Context, operation string, document bson.M, err error) error {
+	if err == nil || c.middleware == nil {
+		return err
+	}
+	for _, mw := range c.middleware.err {
+		if mw.Operation == operation {
+			if e := mw.Handler(ctx, document, err); e != nil {
+				err = e
 			}
 		}
 	}
-	return nil
+	return err
+}
+
+// keys of the values gongo stores in a context
+type contextKey string
+
+const queryContextKey contextKey = "gongo.query"
+
+// QueryContext describes a read operation. pre query middleware can
+// modify the filter, projection and options before the query is sent
+type QueryContext struct {
+	Operation  string
+	Model      *Model
+	Filter     bson.M
+	Projection interface{}
+
+	// Pipeline is the aggregation pipeline for aggregate
+	Pipeline []interface{}
+
+	// Options are the driver options for the operation, for example
+	// *options.FindOptions for find
+	Options interface{}
+}
+
+// QueryFromContext returns the query context of the operation
+// the middleware is being run for
+func QueryFromContext(ctx context.Context) (*QueryContext, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	query, ok := ctx.Value(queryContextKey).(*QueryContext)
+	return query, ok
+}
+
+// applies the pre query middleware and returns a context carrying the query.
+// the filter is passed to the middleware as the document
+func (c *Model) applyPreQueryMiddleware(ctx context.Context, query *QueryContext) (context.Context, error) {
+	ctx = context.WithValue(ctx, queryContextKey, query)
+	if err := c.schema.applyPreMiddleware(ctx, query.Operation, query.Filter); err != nil {
+		return ctx, err
+	}
+	if query.Filter == nil {
+		query.Filter = bson.M{}
+	}
+	return ctx, nil
 }
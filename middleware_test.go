@@ -0,0 +1,362 @@
+package gongo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestMiddlewareChain(t *testing.T) {
+	schema := Schema{
+		Fields: SchemaFieldMap{
+			"name": {
+				Type: StringType,
+			},
+		},
+	}
+	if err := schema.init(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err := schema.Pre("unknown", func(ctx context.Context, doc bson.M, next NextFunc) error {
+		return next()
+	}); err == nil {
+		t.Error("expected error registering unknown operation")
+		return
+	}
+
+	order := make([]string, 0)
+	schema.Pre("save", func(ctx context.Context, doc bson.M, next NextFunc) error {
+		order = append(order, "first")
+		err := next()
+		order = append(order, "first done")
+		return err
+	})
+	schema.Pre("save", func(ctx context.Context, doc bson.M, next NextFunc) error {
+		order = append(order, "second")
+		if doc["name"] == "skip" {
+			return nil
+		}
+		return next()
+	})
+	schema.Pre("save", func(ctx context.Context, doc bson.M, next NextFunc) error {
+		order = append(order, "third")
+		if doc["name"] == "abort" {
+			return errors.New("aborted")
+		}
+		return next()
+	})
+
+	if err := schema.applyPreMiddleware(context.Background(), "save", bson.M{"name": "foo"}); err != nil {
+		t.Error(err)
+		return
+	}
+	expected := []string{"first", "second", "third", "first done"}
+	if len(order) != len(expected) {
+		t.Errorf("expected %v, actual %v", expected, order)
+		return
+	}
+	for i := range expected {
+		if order[i] != expected[i] {
+			t.Errorf("expected %v, actual %v", expected, order)
+			return
+		}
+	}
+
+	// short-circuit skips the remaining middleware
+	order = make([]string, 0)
+	if err := schema.applyPreMiddleware(context.Background(), "save", bson.M{"name": "skip"}); err != nil {
+		t.Error(err)
+		return
+	}
+	if len(order) != 3 {
+		t.Errorf("expected third middleware to be skipped, actual %v", order)
+		return
+	}
+
+	// errors propagate through the chain
+	if err := schema.applyPreMiddleware(context.Background(), "save", bson.M{"name": "abort"}); err == nil || err.Error() != "aborted" {
+		t.Errorf("expected aborted error, actual %v", err)
+		return
+	}
+}
+
+func TestMiddlewareShortCircuit(t *testing.T) {
+	g := New()
+	schema := Schema{
+		Fields: SchemaFieldMap{
+			"name": {
+				Type:     StringType,
+				Required: true,
+			},
+		},
+	}
+	users, err := g.Model("User", &schema)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	// not calling next skips the remaining middleware but not the operation
+	skipped := true
+	users.schema.Pre("validate", func(ctx context.Context, doc bson.M, next NextFunc) error {
+		return nil
+	})
+	users.schema.Pre("validate", func(ctx context.Context, doc bson.M, next NextFunc) error {
+		skipped = false
+		return next()
+	})
+
+	user, err := users.New(bson.M{"_id": primitive.NewObjectID()})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	var verr *ValidationError
+	if err := user.Validate(); !errors.As(err, &verr) {
+		t.Errorf("expected validation to run, actual %v", err)
+		return
+	}
+	if !skipped {
+		t.Error("expected second middleware to be skipped")
+	}
+}
+
+func TestErrorMiddleware(t *testing.T) {
+	errDuplicate := errors.New("name already taken")
+	schema := Schema{}
+	if err := schema.init(); err != nil {
+		t.Error(err)
+		return
+	}
+	schema.OnError("save", func(ctx context.Context, doc bson.M, err error) error {
+		if mongo.IsDuplicateKeyError(err) {
+			return errDuplicate
+		}
+		return nil
+	})
+
+	driverErr := mongo.WriteException{
+		WriteErrors: []mongo.WriteError{{Code: 11000, Message: "E11000 duplicate key error"}},
+	}
+	if err := schema.applyErrorMiddleware(context.Background(), "save", bson.M{}, driverErr); err != errDuplicate {
+		t.Errorf("expected translated error, actual %v", err)
+		return
+	}
+
+	other := errors.New("other")
+	if err := schema.applyErrorMiddleware(context.Background(), "save", bson.M{}, other); err != other {
+		t.Errorf("expected original error, actual %v", err)
+	}
+}
+
+func TestAsyncMiddleware(t *testing.T) {
+	if wait := New().options.AsyncMiddlewareWait; wait != DefaultAsyncMiddlewareWait {
+		t.Errorf("expected default async wait %s, actual %s", DefaultAsyncMiddlewareWait, wait)
+		return
+	}
+
+	g := New(&Options{AsyncMiddlewareWait: 50 * time.Millisecond})
+	schema := Schema{}
+	if err := schema.init(); err != nil {
+		t.Error(err)
+		return
+	}
+	schema.setGongo(g)
+
+	async := true
+	asyncErr := errors.New("async failed")
+	schema.Pre("save", func(ctx context.Context, doc bson.M, next NextFunc) error {
+		// async middleware works on a copy of the document
+		doc["name"] = "changed"
+		return asyncErr
+	}, &async)
+	schema.Pre("save", func(ctx context.Context, doc bson.M, next NextFunc) error {
+		return next()
+	})
+
+	doc := bson.M{"name": "foo"}
+	err := schema.applyPreMiddleware(context.Background(), "save", doc)
+	merr, ok := err.(*MiddlewareError)
+	if !ok {
+		t.Errorf("expected middleware error, actual %v", err)
+		return
+	}
+	if len(merr.Errors) != 1 || !errors.Is(err, asyncErr) {
+		t.Errorf("expected async error, actual %v", merr.Errors)
+		return
+	}
+	if doc["name"] != "foo" {
+		t.Errorf("expected document to be unchanged, actual %v", doc)
+		return
+	}
+
+	// slow async middleware times out
+	schema.Post("save", func(ctx context.Context, doc bson.M, next NextFunc) error {
+		time.Sleep(time.Second)
+		return nil
+	}, &async)
+	if err := schema.applyPostMiddleware(context.Background(), "save", doc); err == nil {
+		t.Error("expected timeout error")
+	}
+}
+
+func TestQueryMiddleware(t *testing.T) {
+	g := New()
+	schema := Schema{
+		Fields: SchemaFieldMap{
+			"name": {
+				Type: StringType,
+			},
+			"age": {
+				Type: IntType,
+			},
+		},
+	}
+	users, err := g.Model("User", &schema)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	// the middleware stops each operation before it reaches the database
+	errStop := errors.New("stop")
+	var queries []*QueryContext
+	for _, op := range []string{"find", "findOne", "count"} {
+		if err := users.schema.Pre(op, func(ctx context.Context, filter bson.M, next NextFunc) error {
+			query, ok := QueryFromContext(ctx)
+			if !ok {
+				return errors.New("missing query context")
+			}
+			filter["deleted"] = bson.M{"$ne": true}
+			query.Projection = bson.M{"name": 1}
+			queries = append(queries, query)
+			return errStop
+		}); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	if _, err := users.Find(bson.M{"age": "10"}); err != errStop {
+		t.Errorf("expected find middleware error, actual %v", err)
+		return
+	}
+	if _, err := users.FindOne(bson.M{"age": "10"}); err != errStop {
+		t.Errorf("expected findOne middleware error, actual %v", err)
+		return
+	}
+	if _, err := users.Query().Where("age").Eq("10").Count(); err != errStop {
+		t.Errorf("expected count middleware error, actual %v", err)
+		return
+	}
+
+	expected := []string{"find", "findOne", "count"}
+	if len(queries) != len(expected) {
+		t.Errorf("expected %d queries, actual %d", len(expected), len(queries))
+		return
+	}
+	for i, query := range queries {
+		if query.Operation != expected[i] || query.Model != users {
+			t.Errorf("expected %s query, actual %s", expected[i], query.Operation)
+			return
+		}
+		if query.Filter["age"] != 10 || query.Filter["deleted"] == nil {
+			t.Errorf("expected cast and modified filter, actual %v", query.Filter)
+			return
+		}
+		if query.Projection == nil || query.Options == nil {
+			t.Errorf("expected projection and options, actual %v %v", query.Projection, query.Options)
+			return
+		}
+	}
+}
+
+func TestDocumentMiddleware(t *testing.T) {
+	g := New()
+	schema := Schema{
+		Fields: SchemaFieldMap{
+			"name": {
+				Type:     StringType,
+				Required: true,
+			},
+		},
+	}
+	users, err := g.Model("User", &schema)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	order := make([]string, 0)
+	invalidate := false
+	users.schema.Pre("init", func(ctx context.Context, doc bson.M, next NextFunc) error {
+		order = append(order, "pre init")
+		if _, ok := doc["name"]; !ok {
+			doc["name"] = "default"
+		}
+		return next()
+	})
+	users.schema.Post("init", func(ctx context.Context, doc bson.M, next NextFunc) error {
+		order = append(order, "post init")
+		return next()
+	})
+	users.schema.Pre("validate", func(ctx context.Context, doc bson.M, next NextFunc) error {
+		order = append(order, "pre validate")
+		if invalidate {
+			delete(doc, "name")
+		}
+		return next()
+	})
+	users.schema.Post("validate", func(ctx context.Context, doc bson.M, next NextFunc) error {
+		order = append(order, "post validate")
+		return next()
+	})
+	users.schema.OnError("validate", func(ctx context.Context, doc bson.M, err error) error {
+		order = append(order, "error validate")
+		return nil
+	})
+
+	user, err := users.New(bson.M{"_id": primitive.NewObjectID()})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if name, _ := user.Get("name"); name != "default" {
+		t.Errorf("expected init middleware to set name, actual %v", name)
+		return
+	}
+
+	// set validates the change without the validate middleware
+	if err := user.Set("name", "bar"); err != nil {
+		t.Error(err)
+		return
+	}
+	if err := user.Validate(); err != nil {
+		t.Error(err)
+		return
+	}
+	invalidate = true
+	if err := user.Validate(); err == nil {
+		t.Error("expected validation error")
+		return
+	}
+
+	expected := []string{"pre init", "post init", "pre validate", "post validate", "pre validate", "error validate"}
+	if len(order) != len(expected) {
+		t.Errorf("expected %v, actual %v", expected, order)
+		return
+	}
+	for i := range expected {
+		if order[i] != expected[i] {
+			t.Errorf("expected %v, actual %v", expected, order)
+			return
+		}
+	}
+}
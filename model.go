@@ -34,11 +34,18 @@ func (c *Model) Collection() *mongo.Collection {
 
 // Create creates and saves a document
 func (c *Model) Create(document interface{}, timeout ...*int) (*Document, error) {
+	ctx, cancelFunc := newContext(timeout...)
+	defer cancelFunc()
+	return c.CreateCtx(ctx, document)
+}
+
+// CreateCtx creates and saves a document using the provided context
+func (c *Model) CreateCtx(ctx context.Context, document interface{}) (*Document, error) {
 	doc, err := c.New(document)
 	if err != nil {
 		return nil, err
 	}
-	if err := doc.Save(timeout...); err != nil {
+	if err := doc.SaveCtx(ctx); err != nil {
 		return doc, err
 	}
 	return doc, nil
@@ -46,50 +53,80 @@ func (c *Model) Create(document interface{}, timeout ...*int) (*Document, error)
 
 // New creates a new instance of a model
 func (c *Model) New(document interface{}) (*Document, error) {
+	return c.hydrate(context.Background(), document)
+}
+
+// creates a new instance of a model wrapped in the init middleware
+func (c *Model) hydrate(ctx context.Context, document interface{}) (*Document, error) {
 	if document == nil {
 		document = &bson.M{}
 	}
 
+	// apply pre-middleware to the raw data
+	raw := bson.M{}
+	if err := c.gongo.weakDecode(document, &raw); err != nil {
+		return nil, err
+	}
+	if err := c.schema.applyPreMiddleware(ctx, "init", raw); err != nil {
+		return nil, err
+	}
+
 	newDocument := Document{model: c}
-	if err := newDocument.load(document, c.schema); err != nil {
+	if err := newDocument.load(raw, c.schema); err != nil {
+		return nil, c.schema.applyErrorMiddleware(ctx, "init", raw, err)
+	}
+
+	// apply post middleware
+	if err := c.schema.applyPostMiddleware(ctx, "init", cloneDocument(newDocument.cur)); err != nil {
 		return nil, err
 	}
 
 	return &newDocument, nil
 }
 
-// Hydrate hydrates a model
-func (c *Model) Hydrate(filter interface{}, timeout ...*int) (*Document, error) {
-	q := bson.M{}
-	if filter != nil {
-		if err := c.gongo.weakDecode(filter, &q); err != nil {
-			return nil, err
-		}
+// creates a new instance of a model from raw data without
+// walking the schema, used by lean queries
+func (c *Model) newLean(document bson.M) *Document {
+	cur := cloneDocument(&document)
+	prev := cloneDocument(&document)
+	next := cloneDocument(&document)
+	return &Document{
+		id:    document["_id"],
+		model: c,
+		prev:  &prev,
+		cur:   &cur,
+		next:  &next,
 	}
+}
 
+// Hydrate hydrates a model
+func (c *Model) Hydrate(filter interface{}, timeout ...*int) (*Document, error) {
 	ctx, cancelFunc := newContext(timeout...)
 	defer cancelFunc()
+	return c.HydrateCtx(ctx, filter)
+}
 
-	// apply virtuals to the filter
-	query, err := c.applyVirtualQueryDocument(&q)
-	if err != nil {
-		return nil, err
-	}
+// HydrateCtx hydrates a model using the provided context
+func (c *Model) HydrateCtx(ctx context.Context, filter interface{}) (*Document, error) {
+	return c.FindOneCtx(ctx, filter)
+}
 
-	// look for the result
-	result := c.Collection().FindOne(ctx, query)
-	if err := result.Err(); err != nil {
-		return nil, err
+// decodes the filter, sanitizes it, and applies virtuals to it
+func (c *Model) buildQuery(ctx context.Context, filter interface{}) (*bson.M, error) {
+	filter, sanitize, strict := c.filterOptions(filter)
+	m := bson.M{}
+	if filter != nil {
+		var err error
+		if m, err = c.filterDocument(filter); err != nil {
+			return nil, err
+		}
 	}
 
-	// hydrate a temp
-	temp := bson.M{}
-	if err := result.Decode(&temp); err != nil {
+	sanitized, err := c.sanitizeQuery(m, sanitize, strict)
+	if err != nil {
 		return nil, err
 	}
-
-	// load the data
-	return c.New(temp)
+	return c.applyVirtualQueryDocument(&sanitized)
 }
 
 // creates indexes
@@ -106,5 +143,10 @@ func (c *Model) createIndexes() error {
 			}
 		}
 	}
+	if len(c.schema.indexes) > 0 {
+		if _, err := c.Collection().Indexes().CreateMany(context.Background(), c.schema.indexes); err != nil {
+			return err
+		}
+	}
 	return nil
 }
@@ -0,0 +1,90 @@
+package gongo
+
+import (
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// PluginFunc a function that modifies a schema, plugins can add
+// fields, virtuals, indexes, methods and middleware
+type PluginFunc func(schema *Schema, opts interface{}) error
+
+// a plugin and the options it is applied with
+type pluginConfig struct {
+	fn   PluginFunc
+	opts interface{}
+}
+
+// Plugin applies a plugin to the schema
+func (c *Schema) Plugin(fn PluginFunc, opts interface{}) error {
+	if fn == nil {
+		return fmt.Errorf("plugin cannot be nil")
+	}
+	if err := c.init(); err != nil {
+		return err
+	}
+	return fn(c, opts)
+}
+
+// Plugin registers a global plugin that is applied to every schema
+// passed to Model. global plugins are applied in the order they were
+// registered and only once per schema
+func (c *Gongo) Plugin(fn PluginFunc, opts interface{}) error {
+	if fn == nil {
+		return fmt.Errorf("plugin cannot be nil")
+	}
+	c.plugins = append(c.plugins, &pluginConfig{
+		fn:   fn,
+		opts: opts,
+	})
+	return nil
+}
+
+// applies the global plugins that have not yet been applied to the schema
+func (c *Gongo) applyPlugins(schema *Schema) error {
+	for _, plugin := range c.plugins {
+		if schema.plugins[plugin] {
+			continue
+		}
+		if err := schema.Plugin(plugin.fn, plugin.opts); err != nil {
+			return err
+		}
+		if schema.plugins == nil {
+			schema.plugins = make(map[*pluginConfig]bool)
+		}
+		schema.plugins[plugin] = true
+	}
+	return nil
+}
+
+// Add adds fields to the schema
+func (c *Schema) Add(fields SchemaFieldMap) error {
+	if c.Fields == nil {
+		c.Fields = SchemaFieldMap{}
+	}
+	for name, field := range fields {
+		if field == nil {
+			return fmt.Errorf("definition for schema field %q cannot be nil", name)
+		} else if _, ok := c.Fields[name]; ok {
+			return fmt.Errorf("schema field %q is already defined", name)
+		} else if _, ok := c.methods[name]; ok {
+			return fmt.Errorf("schema field %q collides with a method", name)
+		} else if _, ok := c.statics[name]; ok {
+			return fmt.Errorf("schema field %q collides with a static", name)
+		}
+		if c.initialized {
+			if err := field.init(name); err != nil {
+				return err
+			}
+		}
+		c.Fields[name] = field
+	}
+	return nil
+}
+
+// Index adds an index that is created when the model is initialized
+func (c *Schema) Index(index mongo.IndexModel) *Schema {
+	c.indexes = append(c.indexes, index)
+	return c
+}
@@ -0,0 +1,94 @@
+package gongo
+
+import (
+	"context"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestPlugins(t *testing.T) {
+	g := New()
+	order := make([]string, 0)
+
+	softDelete := func(schema *Schema, opts interface{}) error {
+		order = append(order, "softDelete")
+		schema.Index(mongo.IndexModel{Keys: bson.D{{Key: "deleted", Value: 1}}})
+		if err := schema.Pre("find", func(ctx context.Context, query bson.M, next NextFunc) error {
+			return next()
+		}); err != nil {
+			return err
+		}
+		return schema.Add(SchemaFieldMap{
+			"deleted": {
+				Type:    BoolType,
+				Default: opts,
+			},
+		})
+	}
+	audit := func(schema *Schema, opts interface{}) error {
+		order = append(order, "audit")
+		schema.Virtual(&VirtualConfig{
+			Name: "audited",
+			Get: func(doc bson.M) (interface{}, error) {
+				return true, nil
+			},
+		})
+		return nil
+	}
+
+	if err := g.Plugin(softDelete, false); err != nil {
+		t.Error(err)
+		return
+	}
+	if err := g.Plugin(audit, nil); err != nil {
+		t.Error(err)
+		return
+	}
+
+	schema := Schema{
+		Fields: SchemaFieldMap{
+			"name": {
+				Type: StringType,
+			},
+		},
+	}
+	users, err := g.Model("User", &schema)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	// global plugins are only applied once per schema
+	if _, err := g.Model("Admin", &schema); err != nil {
+		t.Error(err)
+		return
+	}
+	if len(order) != 2 || order[0] != "softDelete" || order[1] != "audit" {
+		t.Errorf("expected plugins to be applied in order once, actual %v", order)
+		return
+	}
+	if _, ok := users.schema.Fields["deleted"]; !ok {
+		t.Error("expected plugin field to be added")
+		return
+	}
+	if len(users.schema.indexes) != 1 {
+		t.Errorf("expected 1 index, actual %d", len(users.schema.indexes))
+		return
+	}
+
+	user, err := users.New(bson.M{"_id": primitive.NewObjectID(), "name": "foo"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	actual := bson.M{}
+	if err := user.Decode(&actual); err != nil {
+		t.Error(err)
+		return
+	}
+	if actual["audited"] != true {
+		t.Errorf("expected plugin virtual, actual %v", actual)
+	}
+}
@@ -0,0 +1,236 @@
+package gongo
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Populate replaces the references at the specified paths with the
+// referenced documents when the document is decoded
+func (c *Document) Populate(paths ...string) error {
+	return c.PopulateCtx(context.Background(), paths...)
+}
+
+// PopulateCtx populates the specified paths using the provided context
+func (c *Document) PopulateCtx(ctx context.Context, paths ...string) error {
+	list := DocumentList{c}
+	return list.PopulateCtx(ctx, paths...)
+}
+
+// Depopulate removes the populated documents for the specified paths
+// or all paths if none are specified
+func (c *Document) Depopulate(paths ...string) {
+	if len(paths) == 0 {
+		c.populated = nil
+		c.virtuals = nil
+		c.counts = nil
+		return
+	}
+	for _, path := range paths {
+		delete(c.populated, path)
+		delete(c.virtuals, path)
+		delete(c.counts, path)
+	}
+}
+
+// Populated returns the populated documents for a path in the order
+// they are referenced
+func (c *Document) Populated(path string) DocumentList {
+	if list, ok := c.virtuals[path]; ok {
+		return list
+	}
+
+	list := make(DocumentList, 0)
+	refs, ok := c.populated[path]
+	if !ok {
+		return list
+	}
+	doc := cloneDocument(c.cur)
+	mapPathValues(doc, strings.Split(path, "."), func(value interface{}) interface{} {
+		if ref, ok := refs[refKey(value)]; ok {
+			list = append(list, ref)
+		}
+		return value
+	})
+	return list
+}
+
+// Populate populates the specified paths on every document in the list
+func (c *DocumentList) Populate(paths ...string) error {
+	return c.PopulateCtx(context.Background(), paths...)
+}
+
+// PopulateCtx populates the specified paths on every document in the list
+// using the provided context. a single query is made for each path
+func (c *DocumentList) PopulateCtx(ctx context.Context, paths ...string) error {
+	list := *c
+	if len(list) == 0 {
+		return nil
+	}
+	model := list[0].model
+
+	for _, path := range paths {
+		// virtual populate
+		if model.schema.Virtuals != nil {
+			if config, ok := (*model.schema.Virtuals)[path]; ok && config.Ref != "" {
+				if err := list.populateVirtual(ctx, config); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+
+		fieldPath := strings.Split(path, ".")
+		field, _ := model.schema.fieldAtPath(fieldPath)
+		if field == nil {
+			return fmt.Errorf("cannot populate undefined path %q", path)
+		} else if field.Ref == "" {
+			return fmt.Errorf("cannot populate path %q, no ref defined", path)
+		}
+
+		refModel := model.gongo.M(field.Ref)
+		if refModel == nil {
+			return fmt.Errorf("cannot populate path %q, model %q is not registered", path, field.Ref)
+		}
+
+		// collect the unique ids from all documents
+		ids := make([]interface{}, 0)
+		seen := make(map[string]bool)
+		for _, doc := range list {
+			mapPathValues(cloneDocument(doc.cur), fieldPath, func(value interface{}) interface{} {
+				if key := refKey(value); !seen[key] {
+					seen[key] = true
+					ids = append(ids, value)
+				}
+				return value
+			})
+		}
+
+		// find all of the referenced documents
+		refs := make(map[string]*Document)
+		if len(ids) > 0 {
+			results, err := refModel.FindCtx(ctx, Trusted(bson.M{"_id": bson.M{"$in": ids}}))
+			if err != nil {
+				return err
+			}
+			for _, ref := range results {
+				refs[refKey(ref.id)] = ref
+			}
+		}
+
+		for _, doc := range list {
+			if doc.populated == nil {
+				doc.populated = make(map[string]map[string]*Document)
+			}
+			doc.populated[path] = refs
+		}
+	}
+
+	return nil
+}
+
+// populates a virtual with the documents whose foreign field
+// matches the local field of each document in the list
+func (c *DocumentList) populateVirtual(ctx context.Context, config *VirtualConfig) error {
+	list := *c
+	model := list[0].model
+	if config.LocalField == "" || config.ForeignField == "" {
+		return fmt.Errorf("cannot populate virtual %q, local and foreign fields are required", config.Name)
+	}
+
+	refModel := model.gongo.M(config.Ref)
+	if refModel == nil {
+		return fmt.Errorf("cannot populate virtual %q, model %q is not registered", config.Name, config.Ref)
+	}
+
+	// counts are made by the server for each document
+	localPath := strings.Split(config.LocalField, ".")
+	if config.Count {
+		return list.countVirtual(ctx, refModel, config)
+	}
+
+	// collect the unique local values from all documents
+	foreignPath := strings.Split(config.ForeignField, ".")
+	values := make([]interface{}, 0)
+	seen := make(map[string]bool)
+	for _, doc := range list {
+		mapPathValues(cloneDocument(doc.cur), localPath, func(value interface{}) interface{} {
+			if key := refKey(value); !seen[key] {
+				seen[key] = true
+				values = append(values, value)
+			}
+			return value
+		})
+	}
+
+	// find all related documents and group them by foreign value
+	related := make(map[string]DocumentList)
+	if len(values) > 0 {
+		results, err := refModel.FindCtx(ctx, Trusted(bson.M{config.ForeignField: bson.M{"$in": values}}))
+		if err != nil {
+			return err
+		}
+		for _, ref := range results {
+			mapPathValues(cloneDocument(ref.cur), foreignPath, func(value interface{}) interface{} {
+				key := refKey(value)
+				related[key] = append(related[key], ref)
+				return value
+			})
+		}
+	}
+
+	for _, doc := range list {
+		docs := make(DocumentList, 0)
+		added := make(map[*Document]bool)
+		mapPathValues(cloneDocument(doc.cur), localPath, func(value interface{}) interface{} {
+			for _, ref := range related[refKey(value)] {
+				if !added[ref] {
+					added[ref] = true
+					docs = append(docs, ref)
+				}
+			}
+			return value
+		})
+		if doc.virtuals == nil {
+			doc.virtuals = make(map[string]DocumentList)
+		}
+		doc.virtuals[config.Name] = docs
+	}
+
+	return nil
+}
+
+// creates a comparable key for a reference value
+func refKey(value interface{}) string {
+	return fmt.Sprintf("%T:%v", value, value)
+}
+
+// counts the documents whose foreign field matches the local
+// field of each document without loading the related documents
+func (c *DocumentList) countVirtual(ctx context.Context, refModel *Model, config *VirtualConfig) error {
+	localPath := strings.Split(config.LocalField, ".")
+	for _, doc := range *c {
+		values := make([]interface{}, 0)
+		mapPathValues(cloneDocument(doc.cur), localPath, func(value interface{}) interface{} {
+			values = append(values, value)
+			return value
+		})
+
+		var count int64
+		if len(values) > 0 {
+			n, err := refModel.CountCtx(ctx, Trusted(bson.M{config.ForeignField: bson.M{"$in": values}}))
+			if err != nil {
+				return err
+			}
+			count = n
+		}
+		if doc.counts == nil {
+			doc.counts = make(map[string]int64)
+		}
+		doc.counts[config.Name] = count
+	}
+	return nil
+}
@@ -0,0 +1,353 @@
+package gongo
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/bhoriuchi/gongo/helpers"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Query a chainable query builder
+type Query struct {
+	model      *Model
+	filter     bson.M
+	path       string
+	sort       bson.D
+	skip       *int64
+	limit      *int64
+	projection bson.M
+	operators  map[string]bool
+	populate   []string
+	lean       bool
+	err        error
+}
+
+// Query creates a new query builder for the model
+func (c *Model) Query() *Query {
+	return &Query{
+		model:     c,
+		filter:    bson.M{},
+		operators: make(map[string]bool),
+	}
+}
+
+// Where sets the path used by the following condition
+func (c *Query) Where(path string) *Query {
+	if !c.validPath(path, true) {
+		c.setError(fmt.Errorf("query path %q is not defined in the schema", path))
+	}
+	c.path = path
+	return c
+}
+
+// Eq adds an equality condition on the current path
+func (c *Query) Eq(value interface{}) *Query {
+	return c.condition("$eq", value)
+}
+
+// Ne adds a not equal condition on the current path
+func (c *Query) Ne(value interface{}) *Query {
+	return c.condition("$ne", value)
+}
+
+// Gt adds a greater than condition on the current path
+func (c *Query) Gt(value interface{}) *Query {
+	return c.condition("$gt", value)
+}
+
+// Gte adds a greater than or equal condition on the current path
+func (c *Query) Gte(value interface{}) *Query {
+	return c.condition("$gte", value)
+}
+
+// Lt adds a less than condition on the current path
+func (c *Query) Lt(value interface{}) *Query {
+	return c.condition("$lt", value)
+}
+
+// Lte adds a less than or equal condition on the current path
+func (c *Query) Lte(value interface{}) *Query {
+	return c.condition("$lte", value)
+}
+
+// In adds a condition matching any of the values on the current path
+func (c *Query) In(values ...interface{}) *Query {
+	return c.condition("$in", values)
+}
+
+// Nin adds a condition matching none of the values on the current path
+func (c *Query) Nin(values ...interface{}) *Query {
+	return c.condition("$nin", values)
+}
+
+// Exists adds a condition on the existence of the current path
+func (c *Query) Exists(exists bool) *Query {
+	return c.condition("$exists", exists)
+}
+
+// Regex adds a regular expression condition on the current path
+func (c *Query) Regex(pattern string, options ...string) *Query {
+	return c.condition("$regex", primitive.Regex{
+		Pattern: pattern,
+		Options: strings.Join(options, ""),
+	})
+}
+
+// Sort adds sort fields, fields prefixed with - are sorted descending
+func (c *Query) Sort(fields ...string) *Query {
+	for _, field := range fields {
+		order := 1
+		if strings.HasPrefix(field, "-") {
+			order = -1
+			field = strings.TrimPrefix(field, "-")
+		}
+		if !c.validPath(field, false) {
+			c.setError(fmt.Errorf("sort path %q is not defined in the schema", field))
+		}
+		c.sort = append(c.sort, bson.E{Key: field, Value: order})
+	}
+	return c
+}
+
+// Skip sets the number of documents to skip
+func (c *Query) Skip(skip int64) *Query {
+	c.skip = &skip
+	return c
+}
+
+// Limit sets the maximum number of documents to return
+func (c *Query) Limit(limit int64) *Query {
+	c.limit = &limit
+	return c
+}
+
+// Select sets the fields to return, fields prefixed with - are excluded
+func (c *Query) Select(fields ...string) *Query {
+	if c.projection == nil {
+		c.projection = bson.M{}
+	}
+	for _, field := range fields {
+		include := 1
+		if strings.HasPrefix(field, "-") {
+			include = 0
+			field = strings.TrimPrefix(field, "-")
+		}
+		if !c.validPath(field, false) {
+			c.setError(fmt.Errorf("select path %q is not defined in the schema", field))
+		}
+		c.projection[field] = include
+	}
+	return c
+}
+
+// Populate populates the paths on the resulting documents
+func (c *Query) Populate(paths ...string) *Query {
+	c.populate = append(c.populate, paths...)
+	return c
+}
+
+// Lean skips schema validation and casting when loading the
+// resulting documents
+func (c *Query) Lean() *Query {
+	c.lean = true
+	return c
+}
+
+// Filter returns a copy of the current filter
+func (c *Query) Filter() bson.M {
+	return cloneValue(c.filter).(bson.M)
+}
+
+// Exec executes the query and returns the matching documents
+func (c *Query) Exec() (DocumentList, error) {
+	return c.ExecCtx(context.Background())
+}
+
+// ExecCtx executes the query using the provided context
+func (c *Query) ExecCtx(ctx context.Context) (DocumentList, error) {
+	cur, err := c.IterCtx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close()
+
+	results := make(DocumentList, 0)
+	for cur.Next(ctx) {
+		doc, err := cur.Document()
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, doc)
+	}
+	if err := cur.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(c.populate) > 0 {
+		if err := results.PopulateCtx(ctx, c.populate...); err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+// One executes the query and returns the first matching document
+func (c *Query) One() (*Document, error) {
+	return c.OneCtx(context.Background())
+}
+
+// OneCtx executes the query and returns the first matching document
+// using the provided context
+func (c *Query) OneCtx(ctx context.Context) (*Document, error) {
+	limit := c.limit
+	c.Limit(1)
+	results, err := c.ExecCtx(ctx)
+	c.limit = limit
+	if err != nil {
+		return nil, err
+	} else if len(results) == 0 {
+		return nil, mongo.ErrNoDocuments
+	}
+	return results[0], nil
+}
+
+// Count counts the documents matching the query
+func (c *Query) Count() (int64, error) {
+	return c.CountCtx(context.Background())
+}
+
+// CountCtx counts the documents matching the query using the provided context
+func (c *Query) CountCtx(ctx context.Context) (int64, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	opts := options.Count()
+	if c.skip != nil {
+		opts.SetSkip(*c.skip)
+	}
+	if c.limit != nil {
+		opts.SetLimit(*c.limit)
+	}
+	return c.model.CountCtx(ctx, c.trustedFilter(), opts)
+}
+
+// Iter executes the query and returns a cursor over the results
+func (c *Query) Iter() (*DocumentCursor, error) {
+	return c.IterCtx(context.Background())
+}
+
+// IterCtx executes the query and returns a cursor over the results
+// using the provided context
+func (c *Query) IterCtx(ctx context.Context) (*DocumentCursor, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	cur, err := c.model.FindIterCtx(ctx, c.trustedFilter(), c.findOptions())
+	if err != nil {
+		return nil, err
+	}
+	cur.lean = c.lean
+	return cur, nil
+}
+
+// builds the find options from the query
+func (c *Query) findOptions() *options.FindOptions {
+	opts := options.Find()
+	if len(c.sort) > 0 {
+		opts.SetSort(c.sort)
+	}
+	if c.skip != nil {
+		opts.SetSkip(*c.skip)
+	}
+	if c.limit != nil {
+		opts.SetLimit(*c.limit)
+	}
+	if len(c.projection) > 0 {
+		opts.SetProjection(c.projection)
+	}
+	return opts
+}
+
+// returns the filter with the operator documents created by the
+// builder marked as trusted so that they are not sanitized. values
+// passed to the builder are never trusted
+func (c *Query) trustedFilter() bson.M {
+	filter := bson.M{}
+	for path, value := range c.filter {
+		if c.operators[path] {
+			filter[path] = Trusted(value)
+		} else {
+			filter[path] = value
+		}
+	}
+	return filter
+}
+
+// adds an operator condition to the current path
+func (c *Query) condition(operator string, value interface{}) *Query {
+	if c.path == "" {
+		c.setError(fmt.Errorf("no path specified for %s condition, call Where first", operator))
+		return c
+	}
+
+	// only operator documents created by the builder are extended,
+	// an existing equality value becomes an $eq condition
+	conditions, ok := c.filter[c.path].(bson.M)
+	if !ok || !c.operators[c.path] {
+		conditions = bson.M{}
+		if existing, exists := c.filter[c.path]; exists {
+			conditions["$eq"] = existing
+		}
+	}
+
+	// document values are escaped with $eq so that operators
+	// in the value are compared instead of evaluated
+	if operator == "$eq" && len(conditions) == 0 && !isDocumentValue(value) {
+		c.filter[c.path] = value
+		return c
+	}
+	conditions[operator] = value
+	c.filter[c.path] = conditions
+	c.operators[c.path] = true
+	return c
+}
+
+// returns true if the value is a document that could contain operators
+func isDocumentValue(value interface{}) bool {
+	if value == nil {
+		return false
+	} else if _, ok := value.(bson.D); ok {
+		return true
+	}
+	switch rv := helpers.GetElement(value); rv.Kind() {
+	case reflect.Map:
+		return true
+	case reflect.Struct:
+		return !isOpaqueType(rv.Type())
+	}
+	return false
+}
+
+// checks that the path is defined in the schema
+func (c *Query) validPath(path string, allowVirtual bool) bool {
+	if path == "_id" {
+		return true
+	} else if allowVirtual && c.model.schema.keyIsVirtual(path) {
+		return true
+	}
+	field, _ := c.model.schema.fieldAtPath(strings.Split(path, "."))
+	return field != nil
+}
+
+// keeps the first error encountered while building the query
+func (c *Query) setError(err error) {
+	if c.err == nil {
+		c.err = err
+	}
+}
@@ -0,0 +1,100 @@
+package gongo
+
+import (
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestQueryBuilder(t *testing.T) {
+	g := New()
+	fooSchema := Schema{
+		Fields: SchemaFieldMap{
+			"name": {
+				Type: StringType,
+			},
+			"age": {
+				Type: IntType,
+			},
+		},
+	}
+
+	foo, err := g.Model("Foo", &fooSchema)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	q := foo.Query().
+		Where("name").Eq("foo").
+		Where("age").Gt(18).Lte(65).
+		Sort("-age", "name").
+		Select("name", "-age").
+		Skip(10).
+		Limit(5)
+	if q.err != nil {
+		t.Error(q.err)
+		return
+	}
+
+	expected := bson.M{
+		"name": "foo",
+		"age":  bson.M{"$gt": 18, "$lte": 65},
+	}
+	if actual := q.Filter(); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected %v, actual %v", expected, actual)
+		return
+	}
+
+	opts := q.findOptions()
+	expectedSort := bson.D{{Key: "age", Value: -1}, {Key: "name", Value: 1}}
+	if !reflect.DeepEqual(expectedSort, opts.Sort) {
+		t.Errorf("expected %v, actual %v", expectedSort, opts.Sort)
+		return
+	}
+	expectedProjection := bson.M{"name": 1, "age": 0}
+	if !reflect.DeepEqual(expectedProjection, opts.Projection) {
+		t.Errorf("expected %v, actual %v", expectedProjection, opts.Projection)
+		return
+	}
+	if *opts.Skip != 10 || *opts.Limit != 5 {
+		t.Errorf("unexpected skip %d or limit %d", *opts.Skip, *opts.Limit)
+		return
+	}
+
+	// operators in values are compared instead of evaluated
+	q = foo.Query().
+		Where("name").Eq(bson.M{"$ne": nil}).
+		Where("age").Eq(18).Ne(bson.M{"$gt": 0})
+	expected = bson.M{
+		"name": bson.M{"$eq": bson.M{"$ne": nil}},
+		"age":  bson.M{"$eq": 18, "$ne": bson.M{"$gt": 0}},
+	}
+	if actual := q.Filter(); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected %v, actual %v", expected, actual)
+		return
+	}
+	sanitized, err := foo.sanitizeQuery(q.trustedFilter(), true, false)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !reflect.DeepEqual(expected, sanitized) {
+		t.Errorf("expected %v, actual %v", expected, sanitized)
+		return
+	}
+
+	if q := foo.Query().Where("missing").Eq(1); q.err == nil {
+		t.Errorf("expected undefined path to fail")
+		return
+	}
+	if q := foo.Query().Eq(1); q.err == nil {
+		t.Errorf("expected condition without path to fail")
+		return
+	}
+	if q := foo.Query().Where("id").Eq("foo"); q.err != nil {
+		t.Errorf("expected virtual path to be valid, got %v", q.err)
+		return
+	}
+}
@@ -0,0 +1,172 @@
+package gongo
+
+import (
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// FilterOptions overrides the SanitizeFilter and StrictQuery options
+// for a single filter, unset options fall back to the gongo options
+type FilterOptions struct {
+	SanitizeFilter *bool
+	StrictQuery    *bool
+}
+
+// NewFilterOptions creates new filter options
+func NewFilterOptions() *FilterOptions {
+	return &FilterOptions{}
+}
+
+// SetSanitizeFilter sets the SanitizeFilter option
+func (c *FilterOptions) SetSanitizeFilter(sanitize bool) *FilterOptions {
+	c.SanitizeFilter = &sanitize
+	return c
+}
+
+// SetStrictQuery sets the StrictQuery option
+func (c *FilterOptions) SetStrictQuery(strict bool) *FilterOptions {
+	c.StrictQuery = &strict
+	return c
+}
+
+// a filter with options overriding the gongo options
+type optionsFilter struct {
+	value   interface{}
+	options *FilterOptions
+}
+
+// WithFilterOptions wraps a filter so that it is sanitized using the
+// options instead of the gongo options. the filter can also be trusted
+func WithFilterOptions(filter interface{}, opts *FilterOptions) interface{} {
+	return &optionsFilter{value: filter, options: opts}
+}
+
+// marks a filter value as trusted
+type trustedValue struct {
+	value interface{}
+}
+
+// Trusted marks a filter or filter value as trusted so that its
+// operators are not sanitized
+func Trusted(value interface{}) interface{} {
+	return &trustedValue{value: value}
+}
+
+// UnsafeFilterError returned when a sanitized filter contains
+// an operator or path that is not allowed
+type UnsafeFilterError struct {
+	Key     string
+	Message string
+}
+
+// Error implements the error interface
+func (c *UnsafeFilterError) Error() string {
+	return c.Message
+}
+
+// unwraps the option and trusted markers of a filter and returns
+// whether the filter should be sanitized and strict
+func (c *Model) filterOptions(filter interface{}) (interface{}, bool, bool) {
+	sanitize := c.gongo.options.SanitizeFilter
+	strict := c.gongo.options.StrictQuery
+	if f, ok := filter.(*optionsFilter); ok {
+		filter = f.value
+		if f.options != nil && f.options.SanitizeFilter != nil {
+			sanitize = *f.options.SanitizeFilter
+		}
+		if f.options != nil && f.options.StrictQuery != nil {
+			strict = *f.options.StrictQuery
+		}
+	}
+
+	// an entirely trusted filter skips sanitization
+	if t, ok := filter.(*trustedValue); ok {
+		filter = t.value
+		sanitize = false
+	}
+	return filter, sanitize, strict
+}
+
+// sanitizes a query and removes trusted markers. when sanitizing, top level
+// operators other than logical operators are rejected and values containing
+// operators are escaped with $eq. when strict, undefined paths are rejected
+func (c *Model) sanitizeQuery(filter bson.M, sanitize, strict bool) (bson.M, error) {
+	result := bson.M{}
+	for key, value := range filter {
+		// trusted values are unwrapped and left as is
+		if t, ok := value.(*trustedValue); ok {
+			if strict && !strings.HasPrefix(key, "$") && !c.isQueryPath(key) {
+				return nil, &UnsafeFilterError{
+					Key:     key,
+					Message: fmt.Sprintf("query path %q is not defined in the schema", key),
+				}
+			}
+			result[key] = cloneValue(t.value)
+			continue
+		}
+
+		switch {
+		case key == "$and" || key == "$or" || key == "$nor":
+			conditions, ok := cloneValue(value).([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%s requires an array of filters", key)
+			}
+			for i, condition := range conditions {
+				m, ok := condition.(bson.M)
+				if !ok {
+					return nil, fmt.Errorf("%s requires an array of filters", key)
+				}
+				sanitized, err := c.sanitizeQuery(m, sanitize, strict)
+				if err != nil {
+					return nil, err
+				}
+				conditions[i] = sanitized
+			}
+			result[key] = conditions
+
+		case strings.HasPrefix(key, "$"):
+			if sanitize {
+				return nil, &UnsafeFilterError{
+					Key:     key,
+					Message: fmt.Sprintf("query operator %q is not allowed", key),
+				}
+			}
+			result[key] = value
+
+		default:
+			if strict && !c.isQueryPath(key) {
+				return nil, &UnsafeFilterError{
+					Key:     key,
+					Message: fmt.Sprintf("query path %q is not defined in the schema", key),
+				}
+			}
+			v := cloneValue(value)
+			if m, ok := v.(bson.M); ok && sanitize && hasOperatorKey(m) {
+				v = bson.M{"$eq": m}
+			}
+			result[key] = v
+		}
+	}
+	return result, nil
+}
+
+// returns true if the path can be queried
+func (c *Model) isQueryPath(path string) bool {
+	if path == "_id" || c.schema.keyIsVirtual(path) {
+		return true
+	}
+	field, _ := c.schema.fieldAtPath(strings.Split(path, "."))
+	return field != nil
+}
+
+// returns true if any of the document keys are operators
+func hasOperatorKey(doc bson.M) bool {
+	for key := range doc {
+		if strings.HasPrefix(key, "$") {
+			return true
+		}
+	}
+	return false
+}
@@ -4,9 +4,11 @@ import (
 	"fmt"
 	"reflect"
 	"regexp"
+	"time"
 
 	"github.com/bhoriuchi/gongo/helpers"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 // Built-in types
@@ -17,6 +19,11 @@ const (
 	BoolType     = "Bool"
 	MixedType    = "Mixed"
 	ObjectIDType = "ObjectID"
+	DateType     = "Date"
+	LongType     = "Long"
+	DecimalType  = "Decimal"
+	BinaryType   = "Binary"
+	UUIDType     = "UUID"
 )
 
 var schemaTypeReference = Schema{}
@@ -29,6 +36,10 @@ type Schema struct {
 	Options     *SchemaOptions
 	Virtuals    *VirtualFieldMap
 	middleware  *middlewareConfig
+	methods     map[string]MethodFunc
+	statics     map[string]StaticFunc
+	indexes     []mongo.IndexModel
+	plugins     map[*pluginConfig]bool
 	initialized bool
 }
 
@@ -59,6 +70,16 @@ func (c *Schema) init() error {
 	return nil
 }
 
+// sets the gongo reference on the schema and any nested schemas
+func (c *Schema) setGongo(g *Gongo) {
+	c.gongo = g
+	for _, field := range c.Fields {
+		if field.schema != nil {
+			field.schema.setGongo(g)
+		}
+	}
+}
+
 // creates a copy of a schema
 func (c *Schema) copy() *Schema {
 	var options SchemaOptions
@@ -81,12 +102,24 @@ func (c *Schema) copy() *Schema {
 		middleware = middlewareConfig{}
 	}
 
+	methods := make(map[string]MethodFunc)
+	for name, fn := range c.methods {
+		methods[name] = fn
+	}
+	statics := make(map[string]StaticFunc)
+	for name, fn := range c.statics {
+		statics[name] = fn
+	}
+
 	newSchema := Schema{
 		gongo:      c.gongo,
 		Fields:     c.Fields.copy(),
 		Options:    &options,
 		Virtuals:   &virtuals,
 		middleware: &middleware,
+		methods:    methods,
+		statics:    statics,
+		indexes:    append([]mongo.IndexModel{}, c.indexes...),
 	}
 	return &newSchema
 }
@@ -99,8 +132,10 @@ type SchemaField struct {
 	Default     interface{}
 	Validate    *[]ValidatorFunc
 	Meta        *map[string]interface{}
+	Ref         string
 	elementType interface{}
 	isArray     bool
+	schema      *Schema
 }
 
 // initializes a schema field
@@ -127,14 +162,19 @@ func (c *SchemaField) init(name string) error {
 
 	// determine if element type is a valid one
 	if schema := getSchema(c.elementType); schema != nil {
+		if c.Ref != "" {
+			return fmt.Errorf("field %q cannot reference a model when its type is a schema", name)
+		}
 		if err := schema.init(); err != nil {
 			return err
 		}
+		c.schema = schema
 		return nil
 	}
 
 	switch c.elementType {
-	case StringType, IntType, FloatType, BoolType, MixedType, ObjectIDType:
+	case StringType, IntType, FloatType, BoolType, MixedType, ObjectIDType,
+		DateType, LongType, DecimalType, BinaryType, UUIDType:
 		return nil
 	}
 
@@ -163,8 +203,10 @@ func (c *SchemaField) copy() *SchemaField {
 		Default:     c.Default,
 		Validate:    &validators,
 		Meta:        &meta,
+		Ref:         c.Ref,
 		elementType: c.elementType,
 		isArray:     c.isArray,
+		schema:      c.schema,
 	}
 	return &newField
 }
@@ -183,18 +225,78 @@ func (c *SchemaFieldMap) copy() SchemaFieldMap {
 // ValidatorFunc a function that performs a validation
 type ValidatorFunc func(value interface{}) error
 
+// DefaultVersionKey the default document version key
+const DefaultVersionKey = "__v"
+
+// Default timestamp field names
+const (
+	DefaultCreatedAtKey = "createdAt"
+	DefaultUpdatedAtKey = "updatedAt"
+)
+
 // SchemaOptions schema options
 type SchemaOptions struct {
-	ID *bool
+	ID          *bool
+	VersionKey  string
+	DontVersion bool
+	Timestamps  *TimestampsOptions
 }
 
 func (c *SchemaOptions) copy() SchemaOptions {
 	options := SchemaOptions{
-		ID: c.ID,
+		ID:          c.ID,
+		VersionKey:  c.VersionKey,
+		DontVersion: c.DontVersion,
+	}
+	if c.Timestamps != nil {
+		timestamps := *c.Timestamps
+		options.Timestamps = &timestamps
 	}
 	return options
 }
 
+// TimestampsOptions enables automatic created and updated timestamps,
+// Now can be provided to control the clock in tests
+type TimestampsOptions struct {
+	CreatedAt string
+	UpdatedAt string
+	Now       func() time.Time
+}
+
+// returns the created at field name
+func (c *TimestampsOptions) createdAtKey() string {
+	if c.CreatedAt != "" {
+		return c.CreatedAt
+	}
+	return DefaultCreatedAtKey
+}
+
+// returns the updated at field name
+func (c *TimestampsOptions) updatedAtKey() string {
+	if c.UpdatedAt != "" {
+		return c.UpdatedAt
+	}
+	return DefaultUpdatedAtKey
+}
+
+// returns the current time truncated to the precision stored by mongodb
+func (c *TimestampsOptions) now() time.Time {
+	if c.Now != nil {
+		return c.Now().UTC().Truncate(time.Millisecond)
+	}
+	return time.Now().UTC().Truncate(time.Millisecond)
+}
+
+// returns the version key or an empty string if versioning is disabled
+func (c *SchemaOptions) versionKey() string {
+	if c.DontVersion {
+		return ""
+	} else if c.VersionKey != "" {
+		return c.VersionKey
+	}
+	return DefaultVersionKey
+}
+
 // returns a copy of the document with undefined fields removed
 func (c *Schema) copyInternalDocument(doc bson.M) bson.M {
 	newDoc := bson.M{}
@@ -208,6 +310,57 @@ func (c *Schema) copyInternalDocument(doc bson.M) bson.M {
 	return newDoc
 }
 
+// sets the initial version on a new document
+func (c *Schema) setVersion(doc bson.M) {
+	if versionKey := c.Options.versionKey(); versionKey != "" {
+		doc[versionKey] = 0
+	}
+}
+
+// sets the timestamp fields on a document, created at is only
+// set on new documents that do not already have a value
+func (c *Schema) setTimestamps(doc bson.M, isNew bool) {
+	timestamps := c.Options.Timestamps
+	if timestamps == nil {
+		return
+	}
+	now := timestamps.now()
+	if isNew {
+		if v, ok := doc[timestamps.createdAtKey()]; !ok || v == nil {
+			doc[timestamps.createdAtKey()] = now
+		}
+	}
+	doc[timestamps.updatedAtKey()] = now
+}
+
+// adds the timestamp fields to an update document made of update
+// operators. created at is only set if the update results in an insert
+func (c *Schema) setUpdateTimestamps(update bson.M) {
+	timestamps := c.Options.Timestamps
+	if timestamps == nil {
+		return
+	}
+	now := timestamps.now()
+
+	set, ok := cloneValue(update["$set"]).(bson.M)
+	if !ok {
+		set = bson.M{}
+	}
+	if _, ok := set[timestamps.updatedAtKey()]; !ok {
+		set[timestamps.updatedAtKey()] = now
+	}
+	update["$set"] = set
+
+	if _, ok := set[timestamps.createdAtKey()]; !ok {
+		setOnInsert, ok := cloneValue(update["$setOnInsert"]).(bson.M)
+		if !ok {
+			setOnInsert = bson.M{}
+		}
+		setOnInsert[timestamps.createdAtKey()] = now
+		update["$setOnInsert"] = setOnInsert
+	}
+}
+
 // adds default values for missing fields
 func (c *Schema) setDefaults(doc bson.M) {
 	for name, field := range c.Fields {
@@ -247,6 +400,63 @@ func (c *Schema) hasFieldPath(fieldPath []string) bool {
 	return false
 }
 
+// positional update operators and array indexes
+var positionalRx = regexp.MustCompile(`^(\d+|\$|\$\[\]|\$\[[A-Za-z0-9_]*\])$`)
+
+// returns the field at the path. element is true when the path addresses
+// an element of an array field using an index or positional operator.
+// array fields of schemas may also be traversed without an index
+// which is valid in queries
+func (c *Schema) fieldAtPath(fieldPath []string) (*SchemaField, bool) {
+	if len(fieldPath) == 0 {
+		return nil, false
+	}
+
+	field, hasField := c.Fields[fieldPath[0]]
+	if !hasField {
+		return nil, false
+	} else if len(fieldPath) == 1 {
+		return field, false
+	}
+	remaining := fieldPath[1:]
+
+	if field.isArray && positionalRx.MatchString(remaining[0]) {
+		if len(remaining) == 1 {
+			return field, true
+		}
+		remaining = remaining[1:]
+	}
+
+	if field.schema != nil {
+		return field.schema.fieldAtPath(remaining)
+	}
+	return nil, false
+}
+
+// returns true if the path is below a mixed field. mixed
+// fields are schemaless so any path below them is valid
+func (c *Schema) mixedAtPath(fieldPath []string) bool {
+	if len(fieldPath) < 2 {
+		return false
+	}
+
+	field, hasField := c.Fields[fieldPath[0]]
+	if !hasField {
+		return false
+	}
+	remaining := fieldPath[1:]
+
+	if field.isArray && positionalRx.MatchString(remaining[0]) {
+		remaining = remaining[1:]
+	}
+	if field.elementType == MixedType {
+		return len(remaining) > 0
+	} else if field.schema != nil {
+		return field.schema.mixedAtPath(remaining)
+	}
+	return false
+}
+
 // Schema type checker
 func getSchema(obj interface{}) *Schema {
 	el := helpers.GetElement(obj)
@@ -0,0 +1,152 @@
+package gongo
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const transactionKey contextKey = "gongo.transaction"
+
+// tracks the work deferred until a transaction commits and the
+// state of the documents saved before the transaction was attempted
+type transaction struct {
+	mx        sync.Mutex
+	deferred  []func(ctx context.Context) error
+	snapshots map[*Document]*documentState
+}
+
+// the state of a document that changes when it is saved
+type documentState struct {
+	id       interface{}
+	prev     *bson.M
+	cur      *bson.M
+	next     *bson.M
+	modified map[string]bool
+}
+
+// copies a document pointer keeping nil documents nil
+func cloneDocumentPtr(doc *bson.M) *bson.M {
+	if doc == nil {
+		return nil
+	}
+	clone := cloneDocument(doc)
+	return &clone
+}
+
+// records the state of the document the first time it is
+// saved so that it can be restored if the attempt is aborted
+func (c *transaction) snapshot(doc *Document) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	if _, ok := c.snapshots[doc]; ok {
+		return
+	}
+	if c.snapshots == nil {
+		c.snapshots = make(map[*Document]*documentState)
+	}
+	modified := make(map[string]bool)
+	for path := range doc.modified {
+		modified[path] = true
+	}
+	c.snapshots[doc] = &documentState{
+		id:       doc.id,
+		prev:     cloneDocumentPtr(doc.prev),
+		cur:      cloneDocumentPtr(doc.cur),
+		next:     cloneDocumentPtr(doc.next),
+		modified: modified,
+	}
+}
+
+// restores the documents saved during an aborted attempt to
+// their state before the attempt
+func (c *transaction) rollback() {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	for doc, state := range c.snapshots {
+		doc.id = state.id
+		doc.prev = state.prev
+		doc.cur = state.cur
+		doc.next = state.next
+		doc.modified = state.modified
+	}
+	c.snapshots = nil
+}
+
+// records the state of the document when the context belongs to a transaction
+func snapshotDocument(ctx context.Context, doc *Document) {
+	if tx, ok := ctx.Value(transactionKey).(*transaction); ok {
+		tx.snapshot(doc)
+	}
+}
+
+// defers a function until the transaction commits
+func (c *transaction) add(fn func(ctx context.Context) error) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	c.deferred = append(c.deferred, fn)
+}
+
+// runs fn immediately or, when the context belongs to a
+// transaction, after the transaction commits
+func afterCommit(ctx context.Context, fn func(ctx context.Context) error) error {
+	if tx, ok := ctx.Value(transactionKey).(*transaction); ok {
+		tx.add(fn)
+		return nil
+	}
+	return fn(ctx)
+}
+
+// WithTransaction runs fn in a transaction. every Model and Document
+// operation performed with the context passed to fn is bound to the
+// transaction. fn is retried on transient transaction errors so it
+// should not have side effects outside of the database. documents saved
+// in an aborted attempt are restored to their state before the attempt.
+// post save middleware is deferred until the transaction commits
+func (c *Gongo) WithTransaction(ctx context.Context, fn func(txCtx context.Context) error) error {
+	if !c.connected || c.client == nil {
+		return fmt.Errorf("not connected")
+	}
+
+	sess, err := c.client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer sess.EndSession(ctx)
+
+	var tx *transaction
+	if _, err := sess.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		// each attempt starts with a clean transaction state
+		// and the documents saved by the aborted attempt restored
+		if tx != nil {
+			tx.rollback()
+		}
+		tx = &transaction{}
+		return nil, fn(context.WithValue(sc, transactionKey, tx))
+	}); err != nil {
+		if tx != nil {
+			tx.rollback()
+		}
+		return err
+	}
+
+	// run the deferred work now that the transaction has committed
+	errs := make([]error, 0)
+	for _, deferred := range tx.deferred {
+		if err := deferred(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) == 1 {
+		return errs[0]
+	} else if len(errs) > 1 {
+		return &MiddlewareError{
+			Operation: "save",
+			Errors:    errs,
+		}
+	}
+	return nil
+}
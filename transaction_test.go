@@ -0,0 +1,120 @@
+package gongo
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestAfterCommit(t *testing.T) {
+	called := 0
+	fn := func(ctx context.Context) error {
+		called++
+		return nil
+	}
+
+	// outside of a transaction the function runs immediately
+	if err := afterCommit(context.Background(), fn); err != nil {
+		t.Error(err)
+		return
+	}
+	if called != 1 {
+		t.Errorf("expected immediate call, actual %d calls", called)
+		return
+	}
+
+	// inside a transaction the function is deferred
+	tx := &transaction{}
+	ctx := context.WithValue(context.Background(), transactionKey, tx)
+	if err := afterCommit(ctx, fn); err != nil {
+		t.Error(err)
+		return
+	}
+	if called != 1 || len(tx.deferred) != 1 {
+		t.Errorf("expected deferred call, actual %d calls and %d deferred", called, len(tx.deferred))
+		return
+	}
+
+	if err := New().WithTransaction(context.Background(), func(txCtx context.Context) error {
+		return nil
+	}); err == nil {
+		t.Error("expected error starting a transaction when not connected")
+	}
+}
+
+func TestTransactionRollback(t *testing.T) {
+	g := New()
+	fooSchema := Schema{
+		Fields: SchemaFieldMap{
+			"name": {
+				Type: StringType,
+			},
+		},
+	}
+
+	// stop the save before it reaches the server
+	errStop := errors.New("stop")
+	if err := fooSchema.Pre("save", func(ctx context.Context, doc bson.M, next NextFunc) error {
+		return errStop
+	}); err != nil {
+		t.Error(err)
+		return
+	}
+
+	foo, err := g.Model("Foo", &fooSchema)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	doc, err := foo.New(bson.M{"name": "foo"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if err := doc.Set("name", "bar"); err != nil {
+		t.Error(err)
+		return
+	}
+	cur := cloneDocument(doc.cur)
+	next := cloneDocument(doc.next)
+
+	// saving in a transaction records the document state
+	tx := &transaction{}
+	ctx := context.WithValue(context.Background(), transactionKey, tx)
+	if err := doc.SaveCtx(ctx); !errors.Is(err, errStop) {
+		t.Errorf("expected save to be stopped, actual %v", err)
+		return
+	}
+	if _, ok := tx.snapshots[doc]; !ok {
+		t.Errorf("expected document state to be recorded")
+		return
+	}
+
+	// simulate a successful insert in an attempt that is later aborted
+	doc.id = primitive.NewObjectID()
+	if err := doc.moveNext(); err != nil {
+		t.Error(err)
+		return
+	}
+	tx.snapshot(doc)
+
+	tx.rollback()
+	if doc.id != nil {
+		t.Errorf("expected id to be restored, actual %v", doc.id)
+		return
+	} else if !reflect.DeepEqual(cur, *doc.cur) || !reflect.DeepEqual(next, *doc.next) {
+		t.Errorf("expected document to be restored, actual %v %v", *doc.cur, *doc.next)
+		return
+	} else if !doc.IsModified("name") {
+		t.Errorf("expected modified paths to be restored")
+		return
+	}
+	if len(tx.snapshots) != 0 {
+		t.Errorf("expected snapshots to be cleared, actual %d", len(tx.snapshots))
+		return
+	}
+}
@@ -0,0 +1,327 @@
+package gongo
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bhoriuchi/gongo/helpers"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// returned by the type casters when the value is of
+// a type that cannot be converted to the target type
+var errInvalidType = errors.New("invalid type")
+
+// returns true if the type is one of the extended bson types
+func isExtendedType(elementType interface{}) bool {
+	switch elementType {
+	case DateType, LongType, DecimalType, BinaryType, UUIDType:
+		return true
+	}
+	return false
+}
+
+// casts a value to one of the extended bson types
+func castExtendedType(elementType interface{}, value interface{}) (interface{}, error) {
+	switch elementType {
+	case DateType:
+		return castDate(value)
+	case LongType:
+		return castLong(value)
+	case DecimalType:
+		return castDecimal(value)
+	case BinaryType:
+		return castBinary(value)
+	case UUIDType:
+		return castUUID(value)
+	}
+	return nil, errInvalidType
+}
+
+// casts a value to the element type, unlike the document walker
+// strings are converted to numbers and bools. this is used to cast
+// query values which are often sourced from strings
+func castValue(elementType interface{}, value interface{}) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+	el := helpers.GetElement(value)
+
+	switch elementType {
+	case MixedType:
+		return value, nil
+
+	case StringType:
+		switch el.Kind() {
+		case reflect.String:
+			return el.String(), nil
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Float32, reflect.Float64, reflect.Bool:
+			return fmt.Sprintf("%v", el.Interface()), nil
+		}
+
+	case IntType, LongType:
+		switch el.Kind() {
+		case reflect.String:
+			i, err := strconv.ParseInt(el.String(), 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			if elementType == IntType {
+				return int(i), nil
+			}
+			return i, nil
+		case reflect.Float32, reflect.Float64:
+			if f := el.Float(); f == math.Trunc(f) {
+				if elementType == IntType {
+					return int(f), nil
+				}
+				return int64(f), nil
+			}
+			return nil, fmt.Errorf("%v is not an integer", value)
+		}
+		if elementType == LongType {
+			return castLong(value)
+		}
+		switch el.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return value, nil
+		}
+
+	case FloatType:
+		switch el.Kind() {
+		case reflect.String:
+			return strconv.ParseFloat(el.String(), 64)
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return float64(el.Int()), nil
+		case reflect.Float32, reflect.Float64:
+			return el.Float(), nil
+		}
+
+	case BoolType:
+		switch el.Kind() {
+		case reflect.String:
+			return strconv.ParseBool(el.String())
+		case reflect.Bool:
+			return el.Bool(), nil
+		}
+
+	case ObjectIDType:
+		if helpers.IsObjectID(value) {
+			return el.Interface(), nil
+		} else if el.Kind() == reflect.String {
+			return primitive.ObjectIDFromHex(el.String())
+		}
+
+	default:
+		if isExtendedType(elementType) {
+			return castExtendedType(elementType, value)
+		}
+
+		// sub-documents are passed as is
+		if getSchema(elementType) != nil {
+			return value, nil
+		}
+	}
+
+	return nil, errInvalidType
+}
+
+// casts a value to a time.Time
+func castDate(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, nil
+	case *time.Time:
+		return *v, nil
+	case primitive.DateTime:
+		return v.Time().UTC(), nil
+	case string:
+		return time.Parse(time.RFC3339Nano, v)
+	}
+	return nil, errInvalidType
+}
+
+// casts a value to an int64
+func castLong(value interface{}) (interface{}, error) {
+	if _, ok := value.(primitive.DateTime); ok {
+		return nil, errInvalidType
+	}
+	switch kind := helpers.GetKind(value); kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return helpers.GetElement(value).Int(), nil
+	}
+	return nil, errInvalidType
+}
+
+// casts a value to a Decimal128
+func castDecimal(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case primitive.Decimal128:
+		return v, nil
+	case *primitive.Decimal128:
+		return *v, nil
+	case primitive.DateTime:
+		return nil, errInvalidType
+	case string:
+		return primitive.ParseDecimal128(v)
+	}
+	switch kind := helpers.GetKind(value); kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Float32, reflect.Float64:
+		return primitive.ParseDecimal128(fmt.Sprintf("%v", helpers.GetElement(value).Interface()))
+	}
+	return nil, errInvalidType
+}
+
+// casts a value to binary data
+func castBinary(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case primitive.Binary:
+		return v, nil
+	case *primitive.Binary:
+		return *v, nil
+	case []byte:
+		return primitive.Binary{Subtype: 0x00, Data: v}, nil
+	}
+	return nil, errInvalidType
+}
+
+// casts a value to a UUID stored as binary subtype 4
+func castUUID(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case primitive.Binary:
+		if (v.Subtype != 0x04 && v.Subtype != 0x03) || len(v.Data) != 16 {
+			return nil, fmt.Errorf("binary subtype %d is not a valid UUID", v.Subtype)
+		}
+		return v, nil
+	case *primitive.Binary:
+		return castUUID(*v)
+	case []byte:
+		if len(v) != 16 {
+			return nil, fmt.Errorf("UUID must be 16 bytes")
+		}
+		return primitive.Binary{Subtype: 0x04, Data: v}, nil
+	case [16]byte:
+		return primitive.Binary{Subtype: 0x04, Data: v[:]}, nil
+	case string:
+		data, err := hex.DecodeString(strings.ReplaceAll(v, "-", ""))
+		if err != nil {
+			return nil, err
+		} else if len(data) != 16 || (len(v) != 32 && len(v) != 36) {
+			return nil, fmt.Errorf("%q is not a valid UUID", v)
+		}
+		return primitive.Binary{Subtype: 0x04, Data: data}, nil
+	}
+
+	// types like uuid.UUID are defined as [16]byte
+	el := helpers.GetElement(value)
+	if el.Kind() == reflect.Array && el.Len() == 16 && el.Type().Elem().Kind() == reflect.Uint8 {
+		data := make([]byte, 16)
+		reflect.Copy(reflect.ValueOf(data), el)
+		return primitive.Binary{Subtype: 0x04, Data: data}, nil
+	}
+	return nil, errInvalidType
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+var primitivePkgPath = reflect.TypeOf(primitive.DateTime(0)).PkgPath()
+var valueMarshalerType = reflect.TypeOf((*bson.ValueMarshaler)(nil)).Elem()
+var marshalerType = reflect.TypeOf((*bson.Marshaler)(nil)).Elem()
+
+// returns true if values of the type are stored as is instead
+// of being converted to a document
+func isOpaqueType(t reflect.Type) bool {
+	if t == nil {
+		return false
+	}
+	if t.Implements(valueMarshalerType) || t.Implements(marshalerType) {
+		return true
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t == timeType || t.PkgPath() == primitivePkgPath
+}
+
+// converts a struct to a document using the tag for field names.
+// values of bson types such as time.Time and primitive.Decimal128
+// are kept as is so that they can be validated against the schema
+func structToDocument(value reflect.Value, tagName string) interface{} {
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return nil
+		}
+		value = value.Elem()
+	}
+	if !value.IsValid() {
+		return nil
+	} else if isOpaqueType(value.Type()) {
+		return value.Interface()
+	}
+
+	switch value.Kind() {
+	case reflect.Struct:
+		doc := bson.M{}
+		for i := 0; i < value.NumField(); i++ {
+			field := value.Type().Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+			name := field.Name
+			omitEmpty := false
+			if tag, ok := field.Tag.Lookup(tagName); ok {
+				parts := strings.Split(tag, ",")
+				if parts[0] == "-" {
+					continue
+				} else if parts[0] != "" {
+					name = parts[0]
+				}
+				for _, opt := range parts[1:] {
+					omitEmpty = omitEmpty || opt == "omitempty"
+				}
+			}
+			if omitEmpty && value.Field(i).IsZero() {
+				continue
+			}
+			doc[name] = structToDocument(value.Field(i), tagName)
+		}
+		return doc
+
+	case reflect.Slice:
+		if value.IsNil() {
+			return nil
+		} else if value.Type().Elem().Kind() == reflect.Uint8 {
+			return value.Interface()
+		}
+		fallthrough
+
+	case reflect.Array:
+		if value.Type().Elem().Kind() == reflect.Uint8 {
+			return value.Interface()
+		}
+		list := make([]interface{}, 0)
+		for i := 0; i < value.Len(); i++ {
+			list = append(list, structToDocument(value.Index(i), tagName))
+		}
+		return list
+
+	case reflect.Map:
+		if value.IsNil() || value.Type().Key().Kind() != reflect.String {
+			return value.Interface()
+		}
+		doc := bson.M{}
+		for _, key := range value.MapKeys() {
+			doc[key.String()] = structToDocument(value.MapIndex(key), tagName)
+		}
+		return doc
+	}
+	return value.Interface()
+}
@@ -1,9 +1,12 @@
 package gongo
 
 import (
+	"context"
 	"fmt"
+	"strings"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
@@ -17,6 +20,11 @@ func (c *Model) InsertOneWithTimeout(doc interface{}, timeout *int, opts ...*opt
 	return c.Create(doc, timeout)
 }
 
+// InsertOneCtx inserts one document using the provided context
+func (c *Model) InsertOneCtx(ctx context.Context, doc interface{}, opts ...*options.InsertOneOptions) (*Document, error) {
+	return c.CreateCtx(ctx, doc)
+}
+
 // FindOneAndUpdate finds a document and updates it
 func (c *Model) FindOneAndUpdate(
 	filter interface{},
@@ -32,19 +40,24 @@ func (c *Model) FindOneAndUpdateWithTimeout(
 	update interface{},
 	timeout *int,
 	opts ...*options.FindOneAndUpdateOptions,
+) (*Document, error) {
+	ctx, cancelFunc := newContext(timeout)
+	defer cancelFunc()
+	return c.FindOneAndUpdateCtx(ctx, filter, update, opts...)
+}
+
+// FindOneAndUpdateCtx finds a document and updates it using the provided context
+func (c *Model) FindOneAndUpdateCtx(
+	ctx context.Context,
+	filter interface{},
+	update interface{},
+	opts ...*options.FindOneAndUpdateOptions,
 ) (*Document, error) {
 	if update == nil {
 		return nil, fmt.Errorf("no update specified")
 	}
-	m := bson.M{}
-	if filter != nil {
-		if err := c.gongo.weakDecode(filter, &m); err != nil {
-			return nil, err
-		}
-	}
-
 	// get a query
-	query, err := c.applyVirtualQueryDocument(&m)
+	query, err := c.buildQuery(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
@@ -56,36 +69,36 @@ func (c *Model) FindOneAndUpdateWithTimeout(
 	}
 
 	// apply pre-middleware
-	if err := c.schema.applyPreMiddleware("findOneAndUpdate", doc); err != nil {
+	if err := c.schema.applyPreMiddleware(ctx, "findOneAndUpdate", doc); err != nil {
 		return nil, err
 	}
 
 	document, err := c.schema.walk(doc, []string{}, &walkOptions{
 		applySetters:     true,
 		applyDefaults:    false,
-		castObjectID:     true,
+		castTypes:        true,
 		validateTypes:    true,
 		validateCustom:   true,
 		validateRequired: false,
 	})
 
 	if err != nil {
-		return nil, err
+		return nil, c.schema.applyErrorMiddleware(ctx, "findOneAndUpdate", doc, err)
 	}
 
-	// create a context
-	ctx, cancelFunc := newContext(timeout)
-	defer cancelFunc()
+	// stamp the update time
+	updateDoc := bson.M{"$set": document}
+	c.schema.setUpdateTimestamps(updateDoc)
 
 	// perform the find operation
 	result := c.Collection().FindOneAndUpdate(
 		ctx,
 		query,
-		bson.M{"$set": document},
+		updateDoc,
 		opts...,
 	)
 	if err := result.Err(); err != nil {
-		return nil, err
+		return nil, c.schema.applyErrorMiddleware(ctx, "findOneAndUpdate", doc, err)
 	}
 
 	var temp bson.M
@@ -94,12 +107,12 @@ func (c *Model) FindOneAndUpdateWithTimeout(
 	}
 
 	// apply post middleware
-	if err := c.schema.applyPostMiddleware("findOneAndUpdate", temp, nil); err != nil {
+	if err := c.schema.applyPostMiddleware(ctx, "findOneAndUpdate", temp); err != nil {
 		return nil, err
 	}
 
 	// return a new document
-	return c.New(temp)
+	return c.hydrate(ctx, temp)
 }
 
 // FindOneAndDelete finds a document and deletes it
@@ -116,32 +129,32 @@ func (c *Model) FindOneAndDeleteWithTimeout(
 	timeout *int,
 	opts ...*options.FindOneAndDeleteOptions,
 ) (*Document, error) {
-	m := bson.M{}
-	if filter != nil {
-		if err := c.gongo.weakDecode(filter, &m); err != nil {
-			return nil, err
-		}
-	}
+	ctx, cancelFunc := newContext(timeout)
+	defer cancelFunc()
+	return c.FindOneAndDeleteCtx(ctx, filter, opts...)
+}
 
+// FindOneAndDeleteCtx finds a document and deletes it using the provided context
+func (c *Model) FindOneAndDeleteCtx(
+	ctx context.Context,
+	filter interface{},
+	opts ...*options.FindOneAndDeleteOptions,
+) (*Document, error) {
 	// get a query
-	query, err := c.applyVirtualQueryDocument(&m)
+	query, err := c.buildQuery(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
 
 	// apply pre-middleware
-	if err := c.schema.applyPreMiddleware("findOneAndDelete", *query); err != nil {
+	if err := c.schema.applyPreMiddleware(ctx, "findOneAndDelete", *query); err != nil {
 		return nil, err
 	}
 
-	// create a context
-	ctx, cancelFunc := newContext(timeout)
-	defer cancelFunc()
-
 	// perform the update
 	result := c.Collection().FindOneAndDelete(ctx, query, opts...)
 	if err := result.Err(); err != nil {
-		return nil, err
+		return nil, c.schema.applyErrorMiddleware(ctx, "findOneAndDelete", *query, err)
 	}
 
 	var temp bson.M
@@ -150,10 +163,402 @@ func (c *Model) FindOneAndDeleteWithTimeout(
 	}
 
 	// apply post middleware
-	if err := c.schema.applyPostMiddleware("findOneAndDelete", temp, nil); err != nil {
+	if err := c.schema.applyPostMiddleware(ctx, "findOneAndDelete", temp); err != nil {
 		return nil, err
 	}
 
 	// return a new document
-	return c.New(temp)
+	return c.hydrate(ctx, temp)
+}
+
+// UpdateOne updates the first document matching the filter using update operators
+func (c *Model) UpdateOne(
+	filter interface{},
+	update interface{},
+	opts ...*options.UpdateOptions,
+) (*mongo.UpdateResult, error) {
+	return c.UpdateOneCtx(context.Background(), filter, update, opts...)
+}
+
+// UpdateOneCtx updates the first document matching the filter using the provided context
+func (c *Model) UpdateOneCtx(
+	ctx context.Context,
+	filter interface{},
+	update interface{},
+	opts ...*options.UpdateOptions,
+) (*mongo.UpdateResult, error) {
+	return c.updateWithMiddleware(ctx, "updateOne", filter, update, opts...)
+}
+
+// UpdateMany updates all documents matching the filter using update operators
+func (c *Model) UpdateMany(
+	filter interface{},
+	update interface{},
+	opts ...*options.UpdateOptions,
+) (*mongo.UpdateResult, error) {
+	return c.UpdateManyCtx(context.Background(), filter, update, opts...)
+}
+
+// UpdateManyCtx updates all documents matching the filter using the provided context
+func (c *Model) UpdateManyCtx(
+	ctx context.Context,
+	filter interface{},
+	update interface{},
+	opts ...*options.UpdateOptions,
+) (*mongo.UpdateResult, error) {
+	return c.updateWithMiddleware(ctx, "updateMany", filter, update, opts...)
+}
+
+// performs an update operation wrapped in the update middleware. the generic
+// update middleware is applied before the operation specific middleware
+func (c *Model) updateWithMiddleware(
+	ctx context.Context,
+	operation string,
+	filter interface{},
+	update interface{},
+	opts ...*options.UpdateOptions,
+) (*mongo.UpdateResult, error) {
+	query, updateDoc, err := c.prepareUpdate(ctx, operation, filter, update)
+	if err != nil {
+		return nil, err
+	}
+
+	// perform the update
+	var result *mongo.UpdateResult
+	if operation == "updateMany" {
+		result, err = c.Collection().UpdateMany(ctx, query, updateDoc, opts...)
+	} else {
+		result, err = c.Collection().UpdateOne(ctx, query, updateDoc, opts...)
+	}
+	if err != nil {
+		return nil, c.applyUpdateErrorMiddleware(ctx, operation, updateDoc, err)
+	}
+
+	// apply post middleware
+	if err := c.applyUpdatePostMiddleware(ctx, operation, updateDoc); err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// builds the query and validated update document for an update operation
+// after applying the generic and operation specific pre middleware
+func (c *Model) prepareUpdate(ctx context.Context, operation string, filter interface{}, update interface{}) (bson.M, bson.M, error) {
+	if update == nil {
+		return nil, nil, fmt.Errorf("no update specified")
+	}
+
+	// get a query
+	query, err := c.buildQuery(ctx, filter)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// create a working document
+	doc := bson.M{}
+	if err := c.gongo.weakDecode(update, &doc); err != nil {
+		return nil, nil, err
+	}
+
+	// apply pre-middleware
+	for _, op := range []string{"update", operation} {
+		if err := c.schema.applyPreMiddleware(ctx, op, doc); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// validate the update operators
+	updateDoc, err := c.validateUpdate(doc)
+	if err != nil {
+		return nil, nil, c.applyUpdateErrorMiddleware(ctx, operation, doc, err)
+	}
+	c.schema.setUpdateTimestamps(updateDoc)
+	return *query, updateDoc, nil
+}
+
+// applies the generic and operation specific update error middleware
+func (c *Model) applyUpdateErrorMiddleware(ctx context.Context, operation string, document bson.M, err error) error {
+	for _, op := range []string{"update", operation} {
+		err = c.schema.applyErrorMiddleware(ctx, op, document, err)
+	}
+	return err
+}
+
+// applies the generic and operation specific update post middleware
+func (c *Model) applyUpdatePostMiddleware(ctx context.Context, operation string, document bson.M) error {
+	for _, op := range []string{"update", operation} {
+		if err := c.schema.applyPostMiddleware(ctx, op, document); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReplaceOne replaces the first document matching the filter
+func (c *Model) ReplaceOne(
+	filter interface{},
+	replacement interface{},
+	opts ...*options.ReplaceOptions,
+) (*mongo.UpdateResult, error) {
+	return c.ReplaceOneCtx(context.Background(), filter, replacement, opts...)
+}
+
+// ReplaceOneCtx replaces the first document matching the filter using the provided context
+func (c *Model) ReplaceOneCtx(
+	ctx context.Context,
+	filter interface{},
+	replacement interface{},
+	opts ...*options.ReplaceOptions,
+) (*mongo.UpdateResult, error) {
+	query, document, err := c.prepareReplace(ctx, filter, replacement)
+	if err != nil {
+		return nil, err
+	}
+
+	// perform the replace
+	result, err := c.Collection().ReplaceOne(ctx, query, document, opts...)
+	if err != nil {
+		return nil, c.applyUpdateErrorMiddleware(ctx, "replaceOne", document, err)
+	}
+
+	// apply post middleware
+	if err := c.applyUpdatePostMiddleware(ctx, "replaceOne", document); err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// builds the query and validated replacement document for a replace
+// operation after applying the update and replaceOne pre middleware
+func (c *Model) prepareReplace(ctx context.Context, filter interface{}, replacement interface{}) (bson.M, bson.M, error) {
+	if replacement == nil {
+		return nil, nil, fmt.Errorf("no replacement specified")
+	}
+
+	// get a query
+	query, err := c.buildQuery(ctx, filter)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// create a working document
+	doc := bson.M{}
+	if err := c.gongo.weakDecode(replacement, &doc); err != nil {
+		return nil, nil, err
+	}
+	for key := range doc {
+		if strings.HasPrefix(key, "$") {
+			return nil, nil, fmt.Errorf("replacement document cannot contain update operator %q", key)
+		}
+	}
+
+	// apply pre-middleware
+	for _, op := range []string{"update", "replaceOne"} {
+		if err := c.schema.applyPreMiddleware(ctx, op, doc); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// a replacement is a full document
+	c.schema.setTimestamps(doc, true)
+	document, err := c.schema.walk(doc, []string{}, &walkOptions{
+		applySetters:     true,
+		applyDefaults:    true,
+		castTypes:        true,
+		validateTypes:    true,
+		validateCustom:   true,
+		validateRequired: true,
+	})
+	if err != nil {
+		return nil, nil, c.applyUpdateErrorMiddleware(ctx, "replaceOne", doc, err)
+	}
+	delete(*document, "_id")
+	return *query, *document, nil
+}
+
+// validates the target paths and values of an update document made of
+// update operators against the schema and returns the cast update
+func (c *Model) validateUpdate(update bson.M) (bson.M, error) {
+	if len(update) == 0 {
+		return nil, fmt.Errorf("no update specified")
+	}
+
+	verr := newValidationError()
+	result := bson.M{}
+	for op, args := range update {
+		if !strings.HasPrefix(op, "$") {
+			return nil, fmt.Errorf("update document must only contain update operators, found %q", op)
+		}
+
+		fields := bson.M{}
+		if err := c.gongo.weakDecode(args, &fields); err != nil {
+			return nil, fmt.Errorf("invalid arguments for update operator %q: %s", op, err.Error())
+		}
+
+		output := bson.M{}
+		for path, value := range fields {
+			fieldPath := strings.Split(path, ".")
+			if path == "_id" {
+				output[path] = value
+				continue
+			}
+
+			// paths below mixed fields are not type checked
+			if c.schema.mixedAtPath(fieldPath) {
+				output[path] = cloneValue(value)
+				continue
+			}
+
+			field, element := c.schema.fieldAtPath(fieldPath)
+			if field == nil {
+				verr.add(UndefinedErrorKind, fieldPath, value, nil, "update path %q is not defined in the schema", path)
+				continue
+			}
+
+			cast, err := c.validateUpdateOperator(op, field, element, fieldPath, value, verr)
+			if err != nil {
+				return nil, err
+			}
+			output[path] = cloneValue(cast)
+		}
+		result[op] = output
+	}
+
+	if verr.hasErrors() {
+		return nil, verr
+	}
+	return result, nil
+}
+
+// validates the value of a single update operator path
+func (c *Model) validateUpdateOperator(
+	op string,
+	field *SchemaField,
+	element bool,
+	path []string,
+	value interface{},
+	verr *ValidationError,
+) (interface{}, error) {
+	pathStr := strings.Join(path, ".")
+	options := &walkOptions{
+		applySetters:     true,
+		applyDefaults:    false,
+		castTypes:        true,
+		validateTypes:    true,
+		validateCustom:   true,
+		validateRequired: true,
+	}
+
+	// walks the value as either the whole field or a single element
+	walkValue := func(value interface{}, options *walkOptions) (interface{}, error) {
+		if element {
+			return field.walkSingle(value, path, options, verr)
+		}
+		return field.walk(value, path, options, verr)
+	}
+
+	// requires the target to be an array
+	requireArray := func() bool {
+		if !field.isArray || element {
+			verr.add(TypeErrorKind, path, value, nil, "update path %q is not an array", pathStr)
+			return false
+		}
+		return true
+	}
+
+	switch op {
+	case "$set", "$setOnInsert":
+		return walkValue(value, options)
+
+	case "$min", "$max":
+		options.validateRequired = false
+		return walkValue(value, options)
+
+	case "$unset":
+		if field.Required && !element {
+			verr.add(RequiredErrorKind, path, nil, nil, "required document path %q cannot be unset", pathStr)
+		}
+		return "", nil
+
+	case "$inc", "$mul":
+		if field.isArray && !element {
+			verr.add(TypeErrorKind, path, value, nil, "update path %q is not numeric", pathStr)
+			return nil, nil
+		}
+		switch field.elementType {
+		case IntType, FloatType, LongType, DecimalType, MixedType:
+			options.validateCustom = false
+			return field.walkSingle(value, path, options, verr)
+		}
+		verr.add(TypeErrorKind, path, value, nil, "update path %q is not numeric", pathStr)
+		return nil, nil
+
+	case "$push", "$addToSet":
+		if !requireArray() {
+			return nil, nil
+		}
+
+		// handle the $each modifier keeping any other modifiers
+		if modifiers, ok := cloneValue(value).(bson.M); ok {
+			if each, ok := modifiers["$each"]; ok {
+				items, err := field.walkArray(each, path, options, verr)
+				if err != nil {
+					return nil, err
+				}
+				modifiers["$each"] = items
+				return modifiers, nil
+			}
+		}
+		return field.walkSingle(value, childPath(path, "$"), options, verr)
+
+	case "$pull":
+		if !requireArray() {
+			return nil, nil
+		}
+
+		// conditions are passed as is
+		if _, ok := cloneValue(value).(bson.M); ok {
+			return value, nil
+		}
+		options.validateCustom = false
+		return field.walkSingle(value, childPath(path, "$"), options, verr)
+
+	case "$pullAll":
+		if !requireArray() {
+			return nil, nil
+		}
+		options.validateCustom = false
+		return field.walkArray(value, path, options, verr)
+
+	case "$pop":
+		if !requireArray() {
+			return nil, nil
+		}
+		if n, err := castLong(value); err != nil || (n.(int64) != 1 && n.(int64) != -1) {
+			verr.add(TypeErrorKind, path, value, nil, "update path %q $pop value must be 1 or -1", pathStr)
+		}
+		return value, nil
+
+	case "$rename":
+		to, ok := value.(string)
+		if !ok {
+			verr.add(TypeErrorKind, path, value, nil, "update path %q $rename value must be a string", pathStr)
+			return nil, nil
+		}
+		toPath := strings.Split(to, ".")
+		if target, _ := c.schema.fieldAtPath(toPath); target == nil && !c.schema.mixedAtPath(toPath) {
+			verr.add(UndefinedErrorKind, path, value, nil, "rename target %q is not defined in the schema", to)
+		}
+		return value, nil
+
+	case "$currentDate":
+		if field.elementType != DateType && field.elementType != MixedType {
+			verr.add(TypeErrorKind, path, value, nil, "update path %q is not a valid %s", pathStr, DateType)
+		}
+		return value, nil
+	}
+
+	return nil, fmt.Errorf("unsupported update operator %q", op)
 }
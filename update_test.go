@@ -0,0 +1,115 @@
+package gongo
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestValidateUpdate(t *testing.T) {
+	g := New()
+	barSchema := Schema{
+		Fields: SchemaFieldMap{
+			"name": {
+				Type:     StringType,
+				Required: true,
+			},
+		},
+	}
+	fooSchema := Schema{
+		Fields: SchemaFieldMap{
+			"name": {
+				Type:     StringType,
+				Required: true,
+			},
+			"count": {
+				Type: IntType,
+			},
+			"owner": {
+				Type: ObjectIDType,
+			},
+			"tags": {
+				Type: []interface{}{StringType},
+			},
+			"scores": {
+				Type: []interface{}{IntType},
+			},
+			"bars": {
+				Type: []interface{}{barSchema},
+			},
+			"meta": {
+				Type: MixedType,
+			},
+		},
+	}
+
+	foo, err := g.Model("Foo", &fooSchema)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	owner := primitive.NewObjectID()
+	actual, err := foo.validateUpdate(bson.M{
+		"$set":      bson.M{"owner": owner.Hex(), "bars.$.name": "baz", "meta.foo": 1},
+		"$inc":      bson.M{"count": 1, "meta.count": 1, "scores.0": 1},
+		"$unset":    bson.M{"meta.bar": ""},
+		"$rename":   bson.M{"meta.baz": "meta.qux"},
+		"$push":     bson.M{"tags": bson.M{"$each": []interface{}{"a", "b"}, "$slice": -5}},
+		"$addToSet": bson.M{"tags": "c"},
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	expected := bson.M{
+		"$set":      bson.M{"owner": owner, "bars.$.name": "baz", "meta.foo": 1},
+		"$inc":      bson.M{"count": 1, "meta.count": 1, "scores.0": 1},
+		"$unset":    bson.M{"meta.bar": ""},
+		"$rename":   bson.M{"meta.baz": "meta.qux"},
+		"$push":     bson.M{"tags": bson.M{"$each": []interface{}{"a", "b"}, "$slice": -5}},
+		"$addToSet": bson.M{"tags": "c"},
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected %v, actual %v", expected, actual)
+		return
+	}
+
+	_, err = foo.validateUpdate(bson.M{
+		"$set":   bson.M{"count": "one", "missing": 1},
+		"$unset": bson.M{"name": ""},
+		"$inc":   bson.M{"tags": 1, "scores": 1},
+		"$pop":   bson.M{"owner": 1},
+	})
+
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Errorf("expected ValidationError, actual %v", err)
+		return
+	}
+	expectedKinds := map[string]string{
+		"count":   TypeErrorKind,
+		"missing": UndefinedErrorKind,
+		"name":    RequiredErrorKind,
+		"owner":   TypeErrorKind,
+		"tags":    TypeErrorKind,
+		"scores":  TypeErrorKind,
+	}
+	actualKinds := map[string]string{}
+	for path, fieldErr := range verr.Errors {
+		actualKinds[path] = fieldErr.Kind
+	}
+	if !reflect.DeepEqual(expectedKinds, actualKinds) {
+		t.Errorf("expected %v, actual %v", expectedKinds, actualKinds)
+		return
+	}
+
+	if _, err := foo.validateUpdate(bson.M{"name": "foo"}); err == nil {
+		t.Errorf("expected update without operators to fail")
+		return
+	}
+}
@@ -1,11 +1,6 @@
 package gongo
 
-import (
-	"reflect"
-
-	"github.com/bhoriuchi/gongo/helpers"
-	"go.mongodb.org/mongo-driver/bson"
-)
+import "go.mongodb.org/mongo-driver/bson"
 
 // VirtualFieldMap a map of virtual types
 type VirtualFieldMap map[string]*VirtualConfig
@@ -15,9 +10,14 @@ func (c *VirtualFieldMap) copy() VirtualFieldMap {
 	for k, v := range *c {
 		if v != nil {
 			m[k] = &VirtualConfig{
-				Name: v.Name,
-				Get:  v.Get,
-				Set:  v.Set,
+				Name:         v.Name,
+				Get:          v.Get,
+				Set:          v.Set,
+				Ref:          v.Ref,
+				LocalField:   v.LocalField,
+				ForeignField: v.ForeignField,
+				JustOne:      v.JustOne,
+				Count:        v.Count,
 			}
 		} else {
 			m[k] = v
@@ -32,11 +32,19 @@ type VirtualGetFunc func(doc bson.M) (interface{}, error)
 // VirtualSetFunc for resolving virtual
 type VirtualSetFunc func(value interface{}, doc bson.M) error
 
-// VirtualConfig defines the virtual config
+// VirtualConfig defines the virtual config. a virtual with a Ref is populated
+// with the documents of the referenced model whose ForeignField matches
+// the LocalField of the document. JustOne populates a single document
+// and Count populates the number of matching documents
 type VirtualConfig struct {
-	Name string
-	Get  VirtualGetFunc
-	Set  VirtualSetFunc
+	Name         string
+	Get          VirtualGetFunc
+	Set          VirtualSetFunc
+	Ref          string
+	LocalField   string
+	ForeignField string
+	JustOne      bool
+	Count        bool
 }
 
 // returns true if the key name is a registered virtual
@@ -65,8 +73,8 @@ func (c *Schema) Virtual(config *VirtualConfig) *Schema {
 }
 
 // apply virtual setters creates a new document by setting virtual fields
-// and keeping the non-virtual fields
-// TODO: support nested virtuals
+// and keeping the non-virtual fields. virtuals on nested schemas are
+// applied when the nested schema is walked
 func (c *Schema) applyVirtualSetters(doc bson.M) (*bson.M, error) {
 	if c.Virtuals == nil {
 		return &doc, nil
@@ -76,6 +84,10 @@ func (c *Schema) applyVirtualSetters(doc bson.M) (*bson.M, error) {
 	newDoc := bson.M{}
 	for k, v := range doc {
 		if config, ok := virtuals[k]; ok {
+			// virtuals without setters are read only
+			if config.Set == nil {
+				continue
+			}
 			if err := config.Set(v, newDoc); err != nil {
 				return nil, err
 			}
@@ -86,13 +98,37 @@ func (c *Schema) applyVirtualSetters(doc bson.M) (*bson.M, error) {
 	return &newDoc, nil
 }
 
-// apply virtuals getters
+// apply virtuals getters, including virtuals defined on nested
+// schemas and schemas in arrays
 func (c *Schema) applyVirtualGetters(doc bson.M) error {
+	for name, field := range c.Fields {
+		if field.schema == nil {
+			continue
+		}
+		switch v := doc[name].(type) {
+		case bson.M:
+			if err := field.schema.applyVirtualGetters(v); err != nil {
+				return err
+			}
+		case []interface{}:
+			for _, item := range v {
+				if m, ok := item.(bson.M); ok {
+					if err := field.schema.applyVirtualGetters(m); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+
 	if c.Virtuals == nil {
 		return nil
 	}
 
 	for _, v := range *c.Virtuals {
+		if v.Get == nil {
+			continue
+		}
 		value, err := v.Get(doc)
 		if err != nil {
 			return err
@@ -102,69 +138,47 @@ func (c *Schema) applyVirtualGetters(doc bson.M) error {
 	return nil
 }
 
-// converts the filter document to a valid one by replacing string versions of object ids
-// and pointing virtual values to the right keys
+// returns the virtual at the path along with the path of the
+// schema the virtual is defined on
+func (c *Schema) virtualAtPath(fieldPath []string) (*VirtualConfig, *Schema, []string) {
+	if len(fieldPath) == 0 {
+		return nil, nil, nil
+	} else if len(fieldPath) == 1 {
+		if c.Virtuals != nil {
+			if config, ok := (*c.Virtuals)[fieldPath[0]]; ok {
+				return config, c, []string{}
+			}
+		}
+		return nil, nil, nil
+	}
+
+	field, ok := c.Fields[fieldPath[0]]
+	if !ok || field.schema == nil {
+		return nil, nil, nil
+	}
+	remaining := fieldPath[1:]
+	prefix := []string{fieldPath[0]}
+	if field.isArray && positionalRx.MatchString(remaining[0]) {
+		prefix = append(prefix, remaining[0])
+		remaining = remaining[1:]
+	}
+
+	config, schema, path := field.schema.virtualAtPath(remaining)
+	if config == nil {
+		return nil, nil, nil
+	}
+	return config, schema, append(prefix, path...)
+}
+
+// converts the filter document to a valid one by casting values to their
+// schema types and pointing virtual values to the right keys
 func (c *Model) applyVirtualQueryDocument(filter *bson.M) (*bson.M, error) {
 	if filter == nil {
 		return &bson.M{}, nil
 	}
-	query, err := c.deepQueryBuild(*filter)
+	query, err := c.deepQueryBuild(c.schema, cloneDocument(filter), []string{})
 	if err != nil {
 		return nil, err
 	}
-	newFilter := query.(bson.M)
-	return &newFilter, nil
-}
-
-// performs a deep build of the query
-func (c *Model) deepQueryBuild(obj interface{}) (interface{}, error) {
-	// check for object id and return right away
-	if helpers.IsObjectID(obj) {
-		return obj, nil
-	}
-
-	// look at each kind
-	switch kind := helpers.GetKind(obj); kind {
-
-	// handle slice/array
-	case reflect.Slice, reflect.Array:
-		s := reflect.ValueOf(obj)
-		result := make([]interface{}, 0)
-		for i := 0; i < s.Len(); i++ {
-			value, err := c.deepQueryBuild(s.Index(i).Interface())
-			if err != nil {
-				return nil, err
-			}
-			result = append(result, value)
-		}
-		return result, nil
-
-	// handle maps
-	case reflect.Map:
-		result := bson.M{}
-		virtuals := *c.schema.Virtuals
-		original := reflect.ValueOf(obj)
-		for _, key := range original.MapKeys() {
-			k := key.Interface().(string)
-			v := original.MapIndex(key).Interface()
-
-			// get the updated value by calling deep query build on it
-			value, err := c.deepQueryBuild(v)
-			if err != nil {
-				return nil, err
-			}
-
-			// if the value is virtual, use the setter function
-			// otherwise just set the value as is
-			if config, ok := virtuals[k]; ok {
-				if err := config.Set(value, result); err != nil {
-					return nil, err
-				}
-			} else {
-				result[k] = value
-			}
-		}
-		return result, nil
-	}
-	return obj, nil
+	return &query, nil
 }
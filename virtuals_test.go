@@ -0,0 +1,111 @@
+package gongo
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestNestedVirtuals(t *testing.T) {
+	g := New()
+	nameSchema := Schema{
+		Fields: SchemaFieldMap{
+			"first": {
+				Type: StringType,
+			},
+			"last": {
+				Type: StringType,
+			},
+		},
+	}
+	nameSchema.Virtual(&VirtualConfig{
+		Name: "full",
+		Get: func(doc bson.M) (interface{}, error) {
+			return fmt.Sprintf("%v %v", doc["first"], doc["last"]), nil
+		},
+		Set: func(value interface{}, doc bson.M) error {
+			parts := strings.SplitN(fmt.Sprintf("%v", value), " ", 2)
+			doc["first"] = parts[0]
+			if len(parts) > 1 {
+				doc["last"] = parts[1]
+			}
+			return nil
+		},
+	})
+	fooSchema := Schema{
+		Fields: SchemaFieldMap{
+			"name": {
+				Type: nameSchema,
+			},
+			"aliases": {
+				Type: []interface{}{nameSchema},
+			},
+		},
+	}
+
+	foo, err := g.Model("Foo", &fooSchema)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	id := primitive.NewObjectID()
+	doc, err := foo.New(bson.M{
+		"id":      id.Hex(),
+		"name":    bson.M{"full": "John Smith"},
+		"aliases": []interface{}{bson.M{"full": "Johnny Smith"}},
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	// setters are applied at every depth
+	if first, _ := doc.Get("aliases.0.first"); first != "Johnny" {
+		t.Errorf("expected nested setter to be applied, actual %v", first)
+		return
+	}
+
+	// getters are applied at every depth
+	var actual struct {
+		ID   string `json:"id"`
+		Name struct {
+			Full string `json:"full"`
+		} `json:"name"`
+		Aliases []struct {
+			Full string `json:"full"`
+		} `json:"aliases"`
+	}
+	if err := doc.Decode(&actual); err != nil {
+		t.Error(err)
+		return
+	}
+	if actual.ID != id.Hex() || actual.Name.Full != "John Smith" || actual.Aliases[0].Full != "Johnny Smith" {
+		t.Errorf("unexpected decoded document %v", actual)
+		return
+	}
+
+	// virtuals are translated in queries including operators
+	query, err := foo.buildQuery(context.Background(), bson.M{
+		"id":        bson.M{"$in": []interface{}{id.Hex()}},
+		"name.full": "Jane Doe",
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	expected := &bson.M{
+		"_id":        bson.M{"$in": []interface{}{id}},
+		"name.first": "Jane",
+		"name.last":  "Doe",
+	}
+	if !reflect.DeepEqual(expected, query) {
+		t.Errorf("expected %v, actual %v", expected, query)
+		return
+	}
+}
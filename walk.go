@@ -14,14 +14,33 @@ import (
 type walkOptions struct {
 	applySetters     bool
 	applyDefaults    bool
-	castObjectID     bool
+	castTypes        bool
 	validateTypes    bool
 	validateCustom   bool
 	validateRequired bool
 }
 
-// walk walks a schema performing the requested operations
+// walk walks a schema performing the requested operations. validation
+// failures are collected across the entire document and returned as
+// a *ValidationError
 func (c *Schema) walk(value interface{}, path []string, options *walkOptions) (*bson.M, error) {
+	verr := newValidationError()
+	output, err := c.walkDocument(value, path, options, verr)
+	if err != nil {
+		return nil, err
+	} else if verr.hasErrors() {
+		return nil, verr
+	}
+	return output, nil
+}
+
+// walkDocument walks each field in the schema
+func (c *Schema) walkDocument(
+	value interface{},
+	path []string,
+	options *walkOptions,
+	verr *ValidationError,
+) (*bson.M, error) {
 	output := bson.M{}
 	document := bson.M{}
 	if err := c.gongo.weakDecode(value, &document); err != nil {
@@ -44,16 +63,11 @@ func (c *Schema) walk(value interface{}, path []string, options *walkOptions) (*
 	}
 
 	for fieldName, field := range c.Fields {
-		fieldPath := append(path, fieldName)
-		fieldStr := strings.Join(fieldPath, ".")
-		validated, err := field.walk(document[fieldName], fieldPath, options)
+		validated, err := field.walk(document[fieldName], childPath(path, fieldName), options, verr)
 		if err != nil {
 			return nil, err
 		}
 		if validated == nil {
-			if field.Required {
-				return nil, fmt.Errorf("required document path %q not set", fieldStr)
-			}
 			continue
 		}
 
@@ -69,11 +83,12 @@ func (c *SchemaField) walk(
 	value interface{},
 	path []string,
 	options *walkOptions,
+	verr *ValidationError,
 ) (interface{}, error) {
 	if c.isArray {
-		return c.walkArray(value, path, options)
+		return c.walkArray(value, path, options, verr)
 	}
-	return c.walkSingle(value, path, options)
+	return c.walkSingle(value, path, options, verr)
 }
 
 // walkArray walks a schema field that is an array
@@ -81,13 +96,14 @@ func (c *SchemaField) walkArray(
 	value interface{},
 	path []string,
 	options *walkOptions,
+	verr *ValidationError,
 ) (interface{}, error) {
 	pathStr := strings.Join(path, ".")
 
 	// check required value
 	if value == nil {
 		if options.validateRequired && c.Required {
-			return nil, fmt.Errorf("required document path %q not set", pathStr)
+			verr.add(RequiredErrorKind, path, value, nil, "required document path %q not set", pathStr)
 		}
 		return nil, nil
 	}
@@ -95,8 +111,9 @@ func (c *SchemaField) walkArray(
 	// validate that value is an array
 	if !helpers.IsArrayLike(value) {
 		if options.validateTypes {
-			return nil, fmt.Errorf("document path %q is not an array", pathStr)
+			verr.add(TypeErrorKind, path, value, nil, "document path %q is not an array", pathStr)
 		}
+		return nil, nil
 	}
 
 	// iterate through each item in the array
@@ -105,8 +122,9 @@ func (c *SchemaField) walkArray(
 	for i := 0; i < el.Len(); i++ {
 		item, err := c.walkSingle(
 			el.Index(i).Interface(),
-			append(path, fmt.Sprintf("%d", i)),
+			childPath(path, fmt.Sprintf("%d", i)),
 			options,
+			verr,
 		)
 		if err != nil {
 			return nil, err
@@ -124,21 +142,17 @@ func (c *SchemaField) walkSingle(
 	value interface{},
 	path []string,
 	options *walkOptions,
+	verr *ValidationError,
 ) (interface{}, error) {
 	pathStr := strings.Join(path, ".")
 
 	// create a return func
-	var resultFunc = func(value interface{}, err error) (interface{}, error) {
-		// if there is already an error, return it
-		if err != nil {
-			return nil, err
-		}
-
+	var resultFunc = func(value interface{}) (interface{}, error) {
 		// if there is no value
 		if value == nil {
 			// check the required validator
-			if options.validateRequired {
-				return nil, fmt.Errorf("required document path %q not set", pathStr)
+			if options.validateRequired && c.Required {
+				verr.add(RequiredErrorKind, path, value, nil, "required document path %q not set", pathStr)
 			}
 			return nil, nil
 		}
@@ -147,11 +161,20 @@ func (c *SchemaField) walkSingle(
 		if options.validateCustom && c.Validate != nil {
 			for _, validateFunc := range *c.Validate {
 				if err := validateFunc(value); err != nil {
-					return nil, err
+					verr.add(ValidatorErrorKind, path, value, err, "%s", err.Error())
+					return nil, nil
 				}
 			}
 		}
-		return value, err
+		return value, nil
+	}
+
+	// creates an invalid type result
+	var invalidFunc = func(kind string, format string, args ...interface{}) (interface{}, error) {
+		if options.validateTypes {
+			verr.add(kind, path, value, nil, format, args...)
+		}
+		return nil, nil
 	}
 
 	// apply default
@@ -161,7 +184,22 @@ func (c *SchemaField) walkSingle(
 
 	// check required and mixed
 	if value == nil || c.elementType == MixedType {
-		return resultFunc(value, nil)
+		return resultFunc(value)
+	}
+
+	// extended types are checked before kinds since several
+	// of them share a kind with the basic types
+	if isExtendedType(c.elementType) {
+		cast, err := castExtendedType(c.elementType, value)
+		if err == errInvalidType {
+			return invalidFunc(TypeErrorKind, "document path %q is not a valid %s", pathStr, c.elementType)
+		} else if err != nil {
+			return invalidFunc(CastErrorKind, "document path %q failed to cast %s", pathStr, c.elementType)
+		}
+		if options.castTypes {
+			return resultFunc(cast)
+		}
+		return resultFunc(value)
 	}
 
 	// get kinds
@@ -170,78 +208,64 @@ func (c *SchemaField) walkSingle(
 	// array values at this point should only be an objectid
 	case reflect.Array, reflect.Slice:
 		if c.elementType != ObjectIDType {
-			if options.validateTypes {
-				return resultFunc(nil, fmt.Errorf("document path %q is not a valid %s", pathStr, c.elementType))
-			}
-			return resultFunc(nil, nil)
+			return invalidFunc(TypeErrorKind, "document path %q is not a valid %s", pathStr, c.elementType)
 		}
-		return resultFunc(value, nil)
+		return resultFunc(value)
 
 	// string can potentially be an object id
 	case reflect.String:
 		if c.elementType != StringType && c.elementType != ObjectIDType {
-			if options.validateTypes {
-				return resultFunc(nil, fmt.Errorf("document path %q is not a valid %s", pathStr, c.elementType))
-			}
-			return resultFunc(nil, nil)
+			return invalidFunc(TypeErrorKind, "document path %q is not a valid %s", pathStr, c.elementType)
 		}
-		if c.elementType == ObjectIDType && options.castObjectID {
+		if c.elementType == ObjectIDType && options.castTypes {
 			oid, err := primitive.ObjectIDFromHex(value.(string))
 			if err != nil {
-				if options.validateTypes {
-					return resultFunc(nil, fmt.Errorf("document path %q failed to cast ObjectID", pathStr))
-				}
-				return resultFunc(nil, nil)
+				return invalidFunc(CastErrorKind, "document path %q failed to cast ObjectID", pathStr)
 			}
-			return resultFunc(oid, nil)
+			return resultFunc(oid)
 		}
-		return resultFunc(value, nil)
+		return resultFunc(value)
 
 	// bools are bools
 	case reflect.Bool:
 		if c.elementType != BoolType {
-			if options.validateTypes {
-				return resultFunc(nil, fmt.Errorf("document path %q is not a valid %s", pathStr, c.elementType))
-			}
-			return resultFunc(nil, nil)
+			return invalidFunc(TypeErrorKind, "document path %q is not a valid %s", pathStr, c.elementType)
 		}
-		return resultFunc(value, nil)
+		return resultFunc(value)
 
 	// ints are ints
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		if c.elementType != IntType {
-			if options.validateTypes {
-				return resultFunc(nil, fmt.Errorf("document path %q is not a valid %s", pathStr, c.elementType))
-			}
-			return resultFunc(nil, nil)
+		if _, isDate := value.(primitive.DateTime); isDate || c.elementType != IntType {
+			return invalidFunc(TypeErrorKind, "document path %q is not a valid %s", pathStr, c.elementType)
 		}
-		return resultFunc(value, nil)
+		return resultFunc(value)
 
 	// floats are floats
 	case reflect.Float32, reflect.Float64:
 		if c.elementType != FloatType {
-			if options.validateTypes {
-				return resultFunc(nil, fmt.Errorf("document path %q is not a valid %s", pathStr, c.elementType))
-			}
-			return resultFunc(nil, nil)
+			return invalidFunc(TypeErrorKind, "document path %q is not a valid %s", pathStr, c.elementType)
 		}
-		return resultFunc(value, nil)
+		return resultFunc(value)
 
 	// maps should be schema types
 	case reflect.Map:
-		schema := getSchema(c.elementType)
-		if schema == nil {
-			if options.validateTypes {
-				return resultFunc(nil, fmt.Errorf("document path %q is not a valid schema", pathStr))
-			}
-			return resultFunc(nil, nil)
+		if c.schema == nil {
+			return invalidFunc(TypeErrorKind, "document path %q is not a valid schema", pathStr)
 		}
-		subDoc, err := schema.walk(value, path, options)
-		return resultFunc(subDoc, err)
+		subDoc, err := c.schema.walkDocument(value, path, options, verr)
+		if err != nil {
+			return nil, err
+		}
+		return resultFunc(subDoc)
 	}
 
-	if options.validateTypes {
-		return resultFunc(nil, fmt.Errorf("cannot determine data type at document path %q", pathStr))
-	}
-	return resultFunc(nil, nil)
+	return invalidFunc(TypeErrorKind, "cannot determine data type at document path %q", pathStr)
+}
+
+// returns a new path with the key appended without
+// modifying the backing array of the parent path
+func childPath(path []string, key string) []string {
+	p := make([]string, len(path), len(path)+1)
+	copy(p, path)
+	return append(p, key)
 }
@@ -1,10 +1,15 @@
 package gongo
 
 import (
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"reflect"
 	"testing"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 func TestWalkRemoveUndefined(t *testing.T) {
@@ -36,7 +41,7 @@ func TestWalkRemoveUndefined(t *testing.T) {
 	actual, err := fooSchema.walk(fooDoc, []string{}, &walkOptions{
 		applySetters:     true,
 		applyDefaults:    true,
-		castObjectID:     true,
+		castTypes:        true,
 		validateTypes:    true,
 		validateCustom:   true,
 		validateRequired: true,
@@ -99,7 +104,7 @@ func TestWalkNested(t *testing.T) {
 	actual, err := fooSchema.walk(fooDoc, []string{}, &walkOptions{
 		applySetters:     true,
 		applyDefaults:    true,
-		castObjectID:     true,
+		castTypes:        true,
 		validateTypes:    true,
 		validateCustom:   true,
 		validateRequired: true,
@@ -123,3 +128,241 @@ func TestWalkNested(t *testing.T) {
 		return
 	}
 }
+
+func TestWalkValidationError(t *testing.T) {
+	g := New()
+	barSchema := Schema{
+		gongo: g,
+		Fields: SchemaFieldMap{
+			"name": {
+				Type:     StringType,
+				Required: true,
+			},
+		},
+	}
+	fooSchema := Schema{
+		gongo: g,
+		Fields: SchemaFieldMap{
+			"name": {
+				Type:     StringType,
+				Required: true,
+			},
+			"count": {
+				Type: IntType,
+				Validate: &[]ValidatorFunc{
+					func(value interface{}) error {
+						return fmt.Errorf("count is never valid")
+					},
+				},
+			},
+			"owner": {
+				Type: ObjectIDType,
+			},
+			"bars": {
+				Type: []interface{}{barSchema},
+			},
+		},
+	}
+
+	fooDoc := bson.M{
+		"count": 1,
+		"owner": "not-an-id",
+		"bars": []interface{}{
+			bson.M{"name": "ok"},
+			bson.M{"name": 1},
+		},
+	}
+
+	if err := fooSchema.init(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	_, err := fooSchema.walk(fooDoc, []string{}, &walkOptions{
+		applySetters:     true,
+		applyDefaults:    true,
+		castTypes:        true,
+		validateTypes:    true,
+		validateCustom:   true,
+		validateRequired: true,
+	})
+
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Errorf("expected ValidationError, actual %v", err)
+		return
+	}
+
+	expected := map[string]string{
+		"name":        RequiredErrorKind,
+		"count":       ValidatorErrorKind,
+		"owner":       CastErrorKind,
+		"bars.1.name": TypeErrorKind,
+	}
+	actual := map[string]string{}
+	for path, fieldErr := range verr.Errors {
+		actual[path] = fieldErr.Kind
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected %v, actual %v", expected, actual)
+		return
+	}
+}
+
+func TestWalkExtendedTypes(t *testing.T) {
+	g := New()
+	fooSchema := Schema{
+		gongo: g,
+		Fields: SchemaFieldMap{
+			"created": {Type: DateType},
+			"stamp":   {Type: DateType},
+			"count":   {Type: LongType},
+			"price":   {Type: DecimalType},
+			"data":    {Type: BinaryType},
+			"ref":     {Type: UUIDType},
+		},
+	}
+
+	if err := fooSchema.init(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	created := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	actual, err := fooSchema.walk(bson.M{
+		"created": "2020-01-02T03:04:05Z",
+		"stamp":   primitive.NewDateTimeFromTime(created),
+		"count":   int32(5),
+		"price":   "19.99",
+		"data":    []byte("abc"),
+		"ref":     "6ba7b810-9dad-11d1-80b4-00c04fd430c8",
+	}, []string{}, &walkOptions{
+		applySetters:     true,
+		applyDefaults:    true,
+		castTypes:        true,
+		validateTypes:    true,
+		validateCustom:   true,
+		validateRequired: true,
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	price, _ := primitive.ParseDecimal128("19.99")
+	ref, _ := hex.DecodeString("6ba7b8109dad11d180b400c04fd430c8")
+	expected := &bson.M{
+		"created": created,
+		"stamp":   created,
+		"count":   int64(5),
+		"price":   price,
+		"data":    primitive.Binary{Subtype: 0x00, Data: []byte("abc")},
+		"ref":     primitive.Binary{Subtype: 0x04, Data: ref},
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected %v, actual %v", expected, actual)
+		return
+	}
+
+	// strict type validation
+	_, err = fooSchema.walk(bson.M{
+		"created": "yesterday",
+		"count":   "5",
+		"ref":     "not-a-uuid",
+	}, []string{}, &walkOptions{
+		castTypes:     true,
+		validateTypes: true,
+	})
+
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Errorf("expected ValidationError, actual %v", err)
+		return
+	}
+	expectedKinds := map[string]string{
+		"created": CastErrorKind,
+		"count":   TypeErrorKind,
+		"ref":     CastErrorKind,
+	}
+	actualKinds := map[string]string{}
+	for path, fieldErr := range verr.Errors {
+		actualKinds[path] = fieldErr.Kind
+	}
+	if !reflect.DeepEqual(expectedKinds, actualKinds) {
+		t.Errorf("expected %v, actual %v", expectedKinds, actualKinds)
+		return
+	}
+}
+
+func TestWalkExtendedTypesFromStruct(t *testing.T) {
+	g := New()
+	fooSchema := Schema{
+		Fields: SchemaFieldMap{
+			"created": {Type: DateType},
+			"count":   {Type: LongType},
+			"price":   {Type: DecimalType},
+			"data":    {Type: BinaryType},
+			"ref":     {Type: UUIDType},
+			"audit": {Type: Schema{
+				Fields: SchemaFieldMap{
+					"updated": {Type: DateType},
+				},
+			}},
+		},
+	}
+	foos, err := g.Model("Foo", &fooSchema)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	type audit struct {
+		Updated *time.Time `json:"updated"`
+	}
+	created := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	price, _ := primitive.ParseDecimal128("19.99")
+	data := primitive.Binary{Subtype: 0x00, Data: []byte("abc")}
+	ref := [16]byte{0x6b, 0xa7, 0xb8, 0x10, 0x9d, 0xad, 0x11, 0xd1, 0x80, 0xb4, 0x00, 0xc0, 0x4f, 0xd4, 0x30, 0xc8}
+
+	foo, err := foos.New(struct {
+		ID      primitive.ObjectID   `json:"_id"`
+		Created time.Time            `json:"created"`
+		Count   int64                `json:"count"`
+		Price   primitive.Decimal128 `json:"price"`
+		Data    primitive.Binary     `json:"data"`
+		Ref     [16]byte             `json:"ref"`
+		Audit   audit                `json:"audit"`
+	}{
+		ID:      primitive.NewObjectID(),
+		Created: created,
+		Count:   5,
+		Price:   price,
+		Data:    data,
+		Ref:     ref,
+		Audit:   audit{Updated: &created},
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	expected := map[string]interface{}{
+		"created":       created,
+		"count":         int64(5),
+		"price":         price,
+		"data":          data,
+		"ref":           primitive.Binary{Subtype: 0x04, Data: ref[:]},
+		"audit.updated": created,
+	}
+	for path, value := range expected {
+		actual, err := foo.Get(path)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if !reflect.DeepEqual(actual, value) {
+			t.Errorf("expected %s to be %v, actual %v", path, value, actual)
+			return
+		}
+	}
+}